@@ -2,13 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/MemVerge/nf-launcher/pkg/api"
 	configlocal "github.com/MemVerge/nf-launcher/pkg/config"
+	"github.com/MemVerge/nf-launcher/pkg/services"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/batch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 )
@@ -32,9 +41,18 @@ func main() {
 	// Initialize AWS clients
 	batchClient := batch.NewFromConfig(awsCfg)
 	s3Client := s3.NewFromConfig(awsCfg)
+	ecsClient := ecs.NewFromConfig(awsCfg)
+	logsClient := cloudwatchlogs.NewFromConfig(awsCfg)
+
+	// Initialize job store
+	jobStore, err := services.NewJobStore(cfg, s3Client)
+	if err != nil {
+		log.Fatalf("Failed to create job store: %v", err)
+	}
+	log.Printf("Using %s job store", cfg.JobStore)
 
 	// Initialize API
-	apiInstance := api.NewAPI(cfg, batchClient, s3Client)
+	apiInstance := api.NewAPI(cfg, batchClient, s3Client, ecsClient, logsClient, jobStore)
 
 	// Create router
 	router := gin.Default()
@@ -57,9 +75,44 @@ func main() {
 	// Register routes
 	apiInstance.RegisterRoutes(router)
 
+	// Stop background work and the server on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start background workers
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		apiInstance.RunReconciler(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		apiInstance.RunScheduler(ctx)
+	}()
+
 	// Start server
-	log.Printf("Starting server on port %d", cfg.Port)
-	if err := router.Run(fmt.Sprintf(":%d", cfg.Port)); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: router,
+	}
+	go func() {
+		log.Printf("Starting server on port %d", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down server: %v", err)
 	}
+	wg.Wait()
+	// Weblog events are no longer received, so the cached ones are final
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFlush()
+	apiInstance.FlushProgress(flushCtx)
 }
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"sync"
+
 	"github.com/MemVerge/nf-launcher/pkg/config"
+	"github.com/MemVerge/nf-launcher/pkg/services"
 	"github.com/aws/aws-sdk-go-v2/service/batch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 )
@@ -12,14 +17,34 @@ type API struct {
 	config      *config.Config
 	batchClient *batch.Client
 	s3Client    *s3.Client
+	ecsClient   *ecs.Client
+	logsClient  *cloudwatchlogs.Client
+	jobStore    services.JobStore
+
+	// schedulesMu serialises changes to schedules between the API and the
+	// scheduler
+	schedulesMu *sync.Mutex
+
+	// queueMu serialises taking jobs off the queue between the API and the
+	// reconciler
+	queueMu *sync.Mutex
+
+	// progress aggregates the weblog events of running jobs
+	progress *progressCache
 }
 
 // NewAPI creates a new API instance
-func NewAPI(cfg *config.Config, batchClient *batch.Client, s3Client *s3.Client) *API {
+func NewAPI(cfg *config.Config, batchClient *batch.Client, s3Client *s3.Client, ecsClient *ecs.Client, logsClient *cloudwatchlogs.Client, jobStore services.JobStore) *API {
 	return &API{
 		config:      cfg,
 		batchClient: batchClient,
 		s3Client:    s3Client,
+		ecsClient:   ecsClient,
+		logsClient:  logsClient,
+		jobStore:    jobStore,
+		schedulesMu: &sync.Mutex{},
+		queueMu:     &sync.Mutex{},
+		progress:    newProgressCache(),
 	}
 }
 
@@ -41,6 +66,13 @@ func (a *API) RegisterRoutes(router *gin.Engine) {
 		pipelines := v1.Group("/pipelines")
 		{
 			pipelines.GET("", a.ListPipelines)
+			pipelines.POST("", a.CreatePipeline)
+			pipelines.GET("/:name", a.GetPipeline)
+			pipelines.PUT("/:name", a.UpdatePipeline)
+			pipelines.DELETE("/:name", a.DeletePipeline)
+			pipelines.GET("/:name/schema", a.GetPipelineSchema)
+			pipelines.PUT("/:name/schema", a.PutPipelineSchema)
+			pipelines.POST("/:name/schema/import", a.ImportPipelineSchema)
 		}
 
 		// Job routes
@@ -48,8 +80,50 @@ func (a *API) RegisterRoutes(router *gin.Engine) {
 		{
 			jobs.GET("", a.ListJobs)
 			jobs.POST("", a.CreateJob)
+			jobs.GET("/:id", a.GetJobDetail)
 			jobs.GET("/:id/logs", a.GetJobLogs)
+			jobs.GET("/:id/logs/stream", a.StreamJobLogs)
+			jobs.GET("/:id/logs/events", a.GetJobLogEvents)
 			jobs.GET("/:id/log-url", a.GetJobLogPresignedURL)
+			jobs.GET("/:id/artifacts", a.ListJobArtifacts)
+			jobs.DELETE("/:id", a.CancelJob)
+			jobs.POST("/:id/cancel", a.CancelJob)
+			jobs.POST("/:id/resume", a.ResumeJob)
+			jobs.POST("/:id/relaunch", a.RelaunchJob)
+			jobs.GET("/:id/attempts", a.ListJobAttempts)
+			jobs.POST("/:id/weblog", a.IngestWeblog)
+			jobs.GET("/:id/progress", a.GetJobProgress)
+			jobs.GET("/:id/tasks", a.ListJobTasks)
+		}
+
+		// Custom methods on the jobs collection, e.g. /v1/jobs:dry-run
+		v1.POST("/jobs:method", a.JobsMethod)
+
+		// Job group routes
+		jobGroups := v1.Group("/job-groups")
+		{
+			jobGroups.GET("", a.ListJobGroups)
+			jobGroups.POST("", a.CreateJobGroup)
+			jobGroups.GET("/:id", a.GetJobGroup)
+			jobGroups.POST("/:id/cancel", a.CancelJobGroup)
+			jobGroups.POST("/:id/relaunch", a.RelaunchJobGroup)
+		}
+
+		// Schedule routes
+		schedules := v1.Group("/schedules")
+		{
+			schedules.GET("", a.ListSchedules)
+			schedules.POST("", a.CreateSchedule)
+			schedules.GET("/:id", a.GetSchedule)
+			schedules.PUT("/:id", a.UpdateSchedule)
+			schedules.DELETE("/:id", a.DeleteSchedule)
+		}
+
+		// Samplesheet routes
+		samplesheets := v1.Group("/samplesheets")
+		{
+			samplesheets.POST("", a.CreateSamplesheet)
+			samplesheets.GET("/:id", a.GetSamplesheet)
 		}
 
 		// Batch routes
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPresignExpiry is the longest lifetime S3 allows for presigned URLs
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// ArtifactList is the files a job's head node uploaded, with download URLs
+type ArtifactList struct {
+	Bucket    string           `json:"bucket"`
+	Prefix    string           `json:"prefix" example:"jobs/1234/"`
+	Artifacts []types.Artifact `json:"artifacts"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+// @Summary List job artifacts
+// @Description Lists the files under a job's log prefix (nextflow.log, trace.txt, report.html, timeline.html, pipeline_dag.html, ...) with their size, type and a pre-signed download URL
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Param   expires query string false "Lifetime of the URLs, e.g. 1h (default PRESIGN_EXPIRY, max 168h)"
+// @Param   include_cache query bool false "Also list the .nextflow cache kept for resuming"
+// @Success 200 {object} ArtifactList
+// @Router /jobs/{id}/artifacts [get]
+func (a *API) ListJobArtifacts(c *gin.Context) {
+	expiry, err := a.presignExpiry(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	job, err := a.jobStore.GetJob(ctx, c.Param("id"))
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
+	}
+
+	bucket := a.logBucket(job)
+	artifacts, err := services.ListJobArtifacts(ctx, a.s3Client, bucket, job.ID, c.Query("include_cache") == "true")
+	if err != nil {
+		log.Printf("Error listing artifacts of job %s: %v", job.ID, err)
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range artifacts {
+		url, err := a.presignArtifact(ctx, bucket, artifacts[i].Key, expiry)
+		if err != nil {
+			log.Printf("Error generating presigned URL: %v", err)
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		artifacts[i].URL = url
+	}
+
+	c.JSON(200, ArtifactList{
+		Bucket:    bucket,
+		Prefix:    services.ArtifactKey(job.ID, ""),
+		Artifacts: artifacts,
+		ExpiresAt: time.Now().UTC().Add(expiry),
+	})
+}
+
+// presignExpiry reads the lifetime of presigned URLs from the expires
+// query parameter, defaulting to the configured one
+func (a *API) presignExpiry(c *gin.Context) (time.Duration, error) {
+	value := c.Query("expires")
+	if value == "" {
+		return a.config.PresignExpiry, nil
+	}
+	expiry, err := time.ParseDuration(value)
+	if err != nil || expiry <= 0 || expiry > maxPresignExpiry {
+		return 0, fmt.Errorf("expires must be a duration between 1s and %s", maxPresignExpiry)
+	}
+	return expiry, nil
+}
+
+// presignArtifact returns a URL that downloads an object until expiry
+func (a *API) presignArtifact(ctx context.Context, bucket, key string, expiry time.Duration) (string, error) {
+	presignClient := s3.NewPresignClient(a.s3Client)
+	presigned, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", err
+	}
+	return presigned.URL, nil
+}
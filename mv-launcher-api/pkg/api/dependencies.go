@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	batchtypes "github.com/aws/aws-sdk-go-v2/service/batch/types"
+)
+
+// resolveDependencies checks the jobs a job depends on exist and have not
+// failed, and resolves the references to them in its parameters. Jobs the
+// parameters refer to are added to its dependencies.
+func (a *API) resolveDependencies(ctx context.Context, job *types.Job, errs *types.ValidationErrors) error {
+	seen := make(map[string]bool)
+	dependsOn := make([]string, 0, len(job.DependsOn))
+	for _, id := range append(job.DependsOn, services.JobReferences(job.Parameters)...) {
+		if !seen[id] {
+			seen[id] = true
+			dependsOn = append(dependsOn, id)
+		}
+	}
+	if len(dependsOn) == 0 {
+		return nil
+	}
+	job.DependsOn = dependsOn
+
+	dependencies := make(map[string]*types.Job, len(dependsOn))
+	for i, id := range dependsOn {
+		field := fmt.Sprintf("depends_on[%d]", i)
+		if id == job.ID {
+			errs.Add(field, "a job cannot depend on itself")
+			continue
+		}
+		dependency, err := a.jobStore.GetJob(ctx, id)
+		if errors.Is(err, services.ErrJobNotFound) {
+			errs.Add(field, "job %q does not exist", id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if dependency.Status == string(batchtypes.JobStatusFailed) {
+			errs.Add(field, "job %q failed", id)
+		}
+		dependencies[id] = dependency
+	}
+	if len(dependencies) < len(dependsOn) {
+		return nil
+	}
+
+	params, err := services.ResolveJobReferences(job.Parameters, dependencies)
+	if err != nil {
+		errs.Add("parameters", "%v", err)
+		return nil
+	}
+	if len(params) > 0 {
+		job.Parameters = params
+	}
+	return nil
+}
+
+// pendingDependencies returns the jobs a job depends on that have not
+// succeeded yet
+func (a *API) pendingDependencies(ctx context.Context, job *types.Job) ([]string, error) {
+	pending := make([]string, 0)
+	for _, id := range job.DependsOn {
+		dependency, err := a.jobStore.GetJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if dependency.Status != string(batchtypes.JobStatusSucceeded) {
+			pending = append(pending, id)
+		}
+	}
+	return pending, nil
+}
+
+// launchJob submits a job, or queues it until the jobs it depends on have
+// succeeded
+func (a API) launchJob(ctx context.Context, pJob *types.Job) error {
+	pending, err := a.pendingDependencies(ctx, pJob)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		log.Printf("Queueing job %s until %s succeed", pJob.Name, strings.Join(pending, ", "))
+		return a.queueJob(ctx, pJob, "Waiting for jobs "+strings.Join(pending, ", "))
+	}
+	_, err = a.submitJob(ctx, pJob)
+	return err
+}
+
+// dependencyState is where a queued job stands with respect to the jobs it
+// depends on
+type dependencyState int
+
+const (
+	dependenciesSucceeded dependencyState = iota
+	dependenciesPending
+	dependenciesFailed
+)
+
+// checkDependencies works out whether a queued job may run, given every
+// stored job by ID. A dependency that no longer exists counts as failed.
+func checkDependencies(job *types.Job, jobs map[string]*types.Job) (dependencyState, string) {
+	state := dependenciesSucceeded
+	for _, id := range job.DependsOn {
+		dependency, ok := jobs[id]
+		switch {
+		case !ok:
+			return dependenciesFailed, fmt.Sprintf("Dependency %s no longer exists", id)
+		case dependency.Status == string(batchtypes.JobStatusFailed):
+			return dependenciesFailed, fmt.Sprintf("Dependency %s failed", id)
+		case dependency.Status != string(batchtypes.JobStatusSucceeded):
+			state = dependenciesPending
+		}
+	}
+	return state, ""
+}
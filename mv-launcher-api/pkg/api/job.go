@@ -1,19 +1,27 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/MemVerge/nf-launcher/pkg/nfconfig"
 	"github.com/MemVerge/nf-launcher/pkg/services"
 	"github.com/MemVerge/nf-launcher/pkg/types"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/batch"
 	batchtypes "github.com/aws/aws-sdk-go-v2/service/batch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -39,12 +47,33 @@ type JobWithStatus struct {
 	Vcpus           int32     `json:"vcpus,omitempty"`
 }
 
+// JobAttempt is a single AWS Batch attempt of a job's head node
+type JobAttempt struct {
+	ExitCode             *int32    `json:"exit_code,omitempty"`
+	Reason               string    `json:"reason,omitempty"`
+	StatusReason         string    `json:"status_reason,omitempty"`
+	InstanceType         string    `json:"instance_type,omitempty" example:"m5.xlarge"`
+	ContainerInstanceArn string    `json:"container_instance_arn,omitempty"`
+	TaskArn              string    `json:"task_arn,omitempty"`
+	LogStreamName        string    `json:"log_stream_name,omitempty"`
+	StartedAt            time.Time `json:"started_at,omitempty"`
+	StoppedAt            time.Time `json:"stopped_at,omitempty"`
+}
+
+// JobDetail is a stored job spec merged with the live AWS Batch state of its
+// head node, including every attempt
+type JobDetail struct {
+	JobWithStatus
+	AttemptDetails []JobAttempt `json:"attempt_details"`
+}
+
 // @Summary Create a new job
 // @Description Create a new job by uploading a JSON specification
 // @Accept  json
 // @Produce json
 // @Param   job body types.Job true "Job Specification"
 // @Success 201 {object} types.Job
+// @Failure 422 {object} map[string]interface{}
 // @Router /jobs [post]
 func (a API) CreateJob(c *gin.Context) {
 	var pJob types.Job
@@ -53,8 +82,38 @@ func (a API) CreateJob(c *gin.Context) {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	log.Printf("Received job request: %+v", pJob)
+	log.Printf("Received job request: %+v", pJob.Redacted())
+
+	if err := a.validateJob(c.Request.Context(), &pJob); err != nil {
+		log.Printf("Error validating job: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	if err := a.launchJob(c.Request.Context(), &pJob); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return job details
+	c.JSON(200, gin.H{
+		"id":     pJob.ID,
+		"name":   pJob.Name,
+		"status": pJob.Status,
+		"arn":    pJob.BatchJobArn,
+	})
+}
 
+// launchPlan is everything needed to launch the head node of a job
+type launchPlan struct {
+	nextflowConfig string
+	paramsFile     []byte
+	submitInput    *batch.SubmitJobInput
+}
+
+// planLaunch fills in defaults and works out the Nextflow config and the
+// AWS Batch submission for a job, without side effects
+func (a API) planLaunch(ctx context.Context, pJob *types.Job) (*launchPlan, error) {
 	// Set default values if not provided
 	if pJob.Memory == "" {
 		pJob.Memory = "20G"
@@ -62,264 +121,912 @@ func (a API) CreateJob(c *gin.Context) {
 	if pJob.MaxRetries == 0 {
 		pJob.MaxRetries = 5
 	}
-
-	// Store job in S3
-	log.Printf("Storing job in S3 bucket: %s", a.config.JobBucket)
 	// Generate job ID if not provided
 	if pJob.ID == "" {
 		id := uuid.New()
 		pJob.ID = id.String()
 	}
-	err := services.PutJob(a.s3Client, a.config.JobBucket, pJob)
+	if pJob.CreatedAt.IsZero() {
+		pJob.CreatedAt = time.Now().UTC()
+	}
+	pJob.UpdatedAt = pJob.CreatedAt
+
+	nextflowConfig, err := nfconfig.New(*pJob, a.config).Render()
 	if err != nil {
-		log.Printf("Error storing job in S3: %v", err)
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+		return nil, err
+	}
+	paramsFile, err := a.renderParams(pJob)
+	if err != nil {
+		return nil, err
 	}
-	log.Printf("Job %s stored successfully in S3", pJob.ID)
 
-	// Submit job to AWS Batch
 	jobDefinition := fmt.Sprintf("%s-nextflow-headnode", a.config.Environment)
-	log.Printf("Using job definition: %s", jobDefinition)
-
-	// Create job submission
-	jobInput := &batch.SubmitJobInput{
-		JobName:       aws.String(pJob.ID),
-		JobQueue:      aws.String(pJob.HeadNodeQueue),
-		JobDefinition: aws.String(jobDefinition),
-		ContainerOverrides: &batchtypes.ContainerOverrides{
-			Environment: []batchtypes.KeyValuePair{
-				{
-					Name:  aws.String("JOB_ID"),
-					Value: aws.String(pJob.ID),
-				},
-				{
-					Name:  aws.String("PIPELINE"),
-					Value: aws.String(pJob.Pipeline),
-				},
-				{
-					Name:  aws.String("WORK_DIR"),
-					Value: aws.String(pJob.WorkDir),
-				},
-				{
-					Name:  aws.String("RESULT_DIR"),
-					Value: aws.String(pJob.ResultDir),
-				},
-				{
-					Name:  aws.String("LOG_BUCKET"),
-					Value: aws.String(pJob.LogBucket),
-				},
+	environment := []batchtypes.KeyValuePair{
+		{
+			Name:  aws.String("JOB_ID"),
+			Value: aws.String(pJob.ID),
+		},
+		{
+			Name:  aws.String("JOB_BUCKET"),
+			Value: aws.String(a.config.JobBucket),
+		},
+		{
+			Name:  aws.String("PIPELINE"),
+			Value: aws.String(pJob.Repo()),
+		},
+		{
+			Name:  aws.String("REVISION"),
+			Value: aws.String(pJob.Revision),
+		},
+		{
+			Name:  aws.String("PROFILE"),
+			Value: aws.String(pJob.Profile),
+		},
+		{
+			Name:  aws.String("WORK_DIR"),
+			Value: aws.String(pJob.WorkDir),
+		},
+		{
+			Name:  aws.String("RESULT_DIR"),
+			Value: aws.String(pJob.ResultDir),
+		},
+		{
+			Name:  aws.String("LOG_BUCKET"),
+			Value: aws.String(a.logBucket(pJob)),
+		},
+	}
+	if paramsFile != nil {
+		environment = append(environment, batchtypes.KeyValuePair{
+			Name:  aws.String("PARAMS_FILE"),
+			Value: aws.String(nfconfig.ParamsFile),
+		})
+	}
+	if weblogURL := nfconfig.WeblogURL(*pJob, a.config); weblogURL != "" {
+		environment = append(environment, batchtypes.KeyValuePair{
+			Name:  aws.String("WEBLOG_URL"),
+			Value: aws.String(weblogURL),
+		})
+	}
+	if pJob.Resume {
+		environment = append(environment,
+			batchtypes.KeyValuePair{
+				Name:  aws.String("RESUME_FROM"),
+				Value: aws.String(pJob.ParentID),
+			},
+			batchtypes.KeyValuePair{
+				Name:  aws.String("SESSION_ID"),
+				Value: aws.String(pJob.SessionID),
+			},
+		)
+	}
+
+	return &launchPlan{
+		nextflowConfig: nextflowConfig,
+		paramsFile:     paramsFile,
+		submitInput: &batch.SubmitJobInput{
+			JobName:       aws.String(pJob.ID),
+			JobQueue:      aws.String(pJob.HeadNodeQueue),
+			JobDefinition: aws.String(jobDefinition),
+			ContainerOverrides: &batchtypes.ContainerOverrides{
+				Environment: environment,
 			},
 		},
+	}, nil
+}
+
+// renderParams renders the parameters of a job as the params file the head
+// node passes to Nextflow. Values keep their JSON types, which validateJob
+// has already converted to those the pipeline schema declares. It returns
+// nil if the job has no parameters.
+func (a API) renderParams(pJob *types.Job) ([]byte, error) {
+	if len(pJob.Parameters) == 0 {
+		return nil, nil
+	}
+	return json.MarshalIndent(jobParams(pJob), "", "  ")
+}
+
+// jobParams returns a copy of the parameters of a job
+func jobParams(job *types.Job) map[string]interface{} {
+	params := make(map[string]interface{}, len(job.Parameters))
+	for name, value := range job.Parameters {
+		params[name] = value
+	}
+	return params
+}
+
+// submitJob stores the job spec, stages its Nextflow config and submits the
+// head node to AWS Batch. Every path that launches a run goes through here.
+func (a API) submitJob(ctx context.Context, pJob *types.Job) (*batch.SubmitJobOutput, error) {
+	plan, err := a.planLaunch(ctx, pJob)
+	if err != nil {
+		log.Printf("Error planning job launch: %v", err)
+		return nil, err
+	}
+
+	// Store job
+	log.Printf("Storing job in %s job store", a.config.JobStore)
+	if err := a.jobStore.PutJob(ctx, *pJob); err != nil {
+		log.Printf("Error storing job: %v", err)
+		return nil, err
 	}
+	log.Printf("Job %s stored successfully", pJob.ID)
 
-	// Submit the job
-	result, err := a.batchClient.SubmitJob(context.TODO(), jobInput)
+	// Stage the Nextflow config for the head node
+	if err := services.PutJobFile(ctx, a.s3Client, a.config.JobBucket, pJob.ID, "nextflow.config", []byte(plan.nextflowConfig)); err != nil {
+		log.Printf("Error storing Nextflow config: %v", err)
+		return nil, err
+	}
+
+	if plan.paramsFile != nil {
+		if err := services.PutJobFile(ctx, a.s3Client, a.config.JobBucket, pJob.ID, nfconfig.ParamsFile, plan.paramsFile); err != nil {
+			log.Printf("Error storing params file: %v", err)
+			return nil, err
+		}
+	}
+
+	// Submit job to AWS Batch
+	log.Printf("Using job definition: %s", aws.ToString(plan.submitInput.JobDefinition))
+	result, err := a.batchClient.SubmitJob(ctx, plan.submitInput)
 	if err != nil {
 		log.Printf("Error submitting job to AWS Batch: %v", err)
+		return nil, err
+	}
+
+	// Record the Batch job so later lookups don't depend on the queue
+	pJob.BatchJobId = aws.ToString(result.JobId)
+	pJob.BatchJobArn = aws.ToString(result.JobArn)
+	pJob.RecordStatus(string(batchtypes.JobStatusSubmitted), "", time.Now().UTC())
+	if err := a.jobStore.PutJob(ctx, *pJob); err != nil {
+		// The job is running regardless, it will be found by name
+		log.Printf("Error storing batch job id for job %s: %v", pJob.ID, err)
+	}
+	return result, nil
+}
+
+// queueJob stores a job the launcher holds back from AWS Batch, for the
+// reconciler to submit later
+func (a API) queueJob(ctx context.Context, pJob *types.Job, reason string) error {
+	if pJob.ID == "" {
+		pJob.ID = uuid.New().String()
+	}
+	if pJob.CreatedAt.IsZero() {
+		pJob.CreatedAt = time.Now().UTC()
+	}
+	pJob.RecordStatus(types.JobStatusQueued, reason, time.Now().UTC())
+	return a.jobStore.PutJob(ctx, *pJob)
+}
+
+// withQueuedJob calls fn with the stored record of a queued job while
+// holding queueMu, so the reconciler and cancellations each see the other's
+// change. It returns errJobDequeued if the job is no longer queued.
+func (a API) withQueuedJob(ctx context.Context, jobID string, fn func(job *types.Job) error) error {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+
+	job, err := a.jobStore.GetJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Status != types.JobStatusQueued {
+		return errJobDequeued
+	}
+	return fn(job)
+}
+
+// DryRun is what submitting a job would do
+type DryRun struct {
+	Job            types.Job         `json:"job"`
+	NextflowConfig string            `json:"nextflow_config"`
+	ParamsFile     json.RawMessage   `json:"params_file,omitempty"`
+	Command        []string          `json:"command"`
+	JobDefinition  string            `json:"job_definition" example:"dev-nextflow-headnode"`
+	JobQueue       string            `json:"job_queue"`
+	Environment    map[string]string `json:"environment"`
+}
+
+// JobsMethod dispatches custom methods on the jobs collection, such as
+// POST /v1/jobs:dry-run. Gin 1.9 cannot escape ':' in routes, so the suffix
+// after "jobs" arrives as the method param.
+func (a *API) JobsMethod(c *gin.Context) {
+	switch c.Param("method") {
+	case ":dry-run":
+		a.DryRunJob(c)
+	default:
+		c.JSON(404, gin.H{"error": "Not found"})
+	}
+}
+
+// @Summary Preview a job submission
+// @Description Validate a job and return the Nextflow config, command line and AWS Batch submission it would run with. Nothing is stored or submitted.
+// @Accept  json
+// @Produce json
+// @Param   job body types.Job true "Job Specification"
+// @Success 200 {object} DryRun
+// @Router /jobs:dry-run [post]
+func (a *API) DryRunJob(c *gin.Context) {
+	var pJob types.Job
+	if err := c.ShouldBindJSON(&pJob); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := a.validateJob(c.Request.Context(), &pJob); err != nil {
+		log.Printf("Error validating job: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	plan, err := a.planLaunch(c.Request.Context(), &pJob)
+	if err != nil {
+		log.Printf("Error planning job launch: %v", err)
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return job details
+	environment := make(map[string]string)
+	for _, kv := range plan.submitInput.ContainerOverrides.Environment {
+		environment[aws.ToString(kv.Name)] = aws.ToString(kv.Value)
+	}
+
+	c.JSON(200, DryRun{
+		Job:            pJob.Redacted(),
+		NextflowConfig: plan.nextflowConfig,
+		ParamsFile:     plan.paramsFile,
+		Command:        nfconfig.Command(pJob, a.config),
+		JobDefinition:  aws.ToString(plan.submitInput.JobDefinition),
+		JobQueue:       aws.ToString(plan.submitInput.JobQueue),
+		Environment:    environment,
+	})
+}
+
+// @Summary Get a job
+// @Description Returns the stored job spec merged with the live AWS Batch state of its head node, including every attempt
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Success 200 {object} JobDetail
+// @Router /jobs/{id} [get]
+func (a *API) GetJobDetail(c *gin.Context) {
+	jobID := c.Param("id")
+	if jobID == "" {
+		c.JSON(400, gin.H{"error": "Job ID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	jobSpec, err := a.jobStore.GetJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
+	}
+
+	detail := JobDetail{
+		JobWithStatus:  newJobWithStatus(*jobSpec),
+		AttemptDetails: make([]JobAttempt, 0),
+	}
+
+	batchJobID, err := a.findBatchJobID(ctx, jobSpec)
+	if err != nil {
+		// Not submitted yet, or purged from AWS Batch; the spec is all we have
+		log.Printf("No batch job found for %s: %v", jobID, err)
+		c.JSON(200, detail)
+		return
+	}
+
+	describeOutput, err := a.batchClient.DescribeJobs(ctx, &batch.DescribeJobsInput{
+		Jobs: []string{batchJobID},
+	})
+	if err != nil {
+		log.Printf("Error describing job: %v", err)
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if len(describeOutput.Jobs) == 0 {
+		log.Printf("Batch job %s of %s no longer exists", batchJobID, jobID)
+		c.JSON(200, detail)
+		return
+	}
+
+	batchJob := describeOutput.Jobs[0]
+	a.syncJobStatus(ctx, jobSpec, batchJob)
+	detail.JobWithStatus = newJobWithStatus(*jobSpec)
+	detail.AttemptDetails = newJobAttempts(batchJob)
+
+	arns := make([]string, 0, len(detail.AttemptDetails))
+	for _, attempt := range detail.AttemptDetails {
+		if attempt.ContainerInstanceArn != "" {
+			arns = append(arns, attempt.ContainerInstanceArn)
+		}
+	}
+	instanceTypes := a.lookupInstanceTypes(ctx, arns)
+	for i := range detail.AttemptDetails {
+		detail.AttemptDetails[i].InstanceType = instanceTypes[detail.AttemptDetails[i].ContainerInstanceArn]
+	}
+
+	c.JSON(200, detail)
+}
+
+// newJobAttempts lists the finished attempts of a Batch job, followed by the
+// current one if the job is still running
+func newJobAttempts(job batchtypes.JobDetail) []JobAttempt {
+	attempts := make([]JobAttempt, 0, len(job.Attempts)+1)
+	for _, detail := range job.Attempts {
+		attempt := JobAttempt{
+			StatusReason: aws.ToString(detail.StatusReason),
+		}
+		if detail.StartedAt != nil {
+			attempt.StartedAt = time.UnixMilli(*detail.StartedAt)
+		}
+		if detail.StoppedAt != nil {
+			attempt.StoppedAt = time.UnixMilli(*detail.StoppedAt)
+		}
+		if detail.Container != nil {
+			attempt.ExitCode = detail.Container.ExitCode
+			attempt.Reason = aws.ToString(detail.Container.Reason)
+			attempt.ContainerInstanceArn = aws.ToString(detail.Container.ContainerInstanceArn)
+			attempt.TaskArn = aws.ToString(detail.Container.TaskArn)
+			attempt.LogStreamName = aws.ToString(detail.Container.LogStreamName)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	// Finished attempts are only recorded once they stop
+	if !isTerminalStatus(job.Status) && job.Container != nil && job.Container.TaskArn != nil {
+		attempt := JobAttempt{
+			ContainerInstanceArn: aws.ToString(job.Container.ContainerInstanceArn),
+			TaskArn:              aws.ToString(job.Container.TaskArn),
+			LogStreamName:        aws.ToString(job.Container.LogStreamName),
+		}
+		if job.StartedAt != nil {
+			attempt.StartedAt = time.UnixMilli(*job.StartedAt)
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts
+}
+
+// lookupInstanceTypes resolves ECS container instance ARNs to EC2 instance
+// types. Instances that are gone or on Fargate are left out.
+func (a *API) lookupInstanceTypes(ctx context.Context, arns []string) map[string]string {
+	// arn:aws:ecs:<region>:<account>:container-instance/<cluster>/<id>
+	byCluster := make(map[string][]string)
+	for _, arn := range arns {
+		parts := strings.Split(arn, "/")
+		if len(parts) != 3 {
+			continue
+		}
+		byCluster[parts[1]] = append(byCluster[parts[1]], arn)
+	}
+
+	instanceTypes := make(map[string]string)
+	for cluster, clusterArns := range byCluster {
+		out, err := a.ecsClient.DescribeContainerInstances(ctx, &ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(cluster),
+			ContainerInstances: clusterArns,
+		})
+		if err != nil {
+			log.Printf("Error describing container instances in cluster %s: %v", cluster, err)
+			continue
+		}
+		for _, instance := range out.ContainerInstances {
+			for _, attr := range instance.Attributes {
+				if aws.ToString(attr.Name) == "ecs.instance-type" {
+					instanceTypes[aws.ToString(instance.ContainerInstanceArn)] = aws.ToString(attr.Value)
+				}
+			}
+		}
+	}
+	return instanceTypes
+}
+
+// @Summary Resume a job
+// @Description Launch a new attempt of a finished job with nextflow -resume, reusing its work directory, session ID and .nextflow cache
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /jobs/{id}/resume [post]
+func (a *API) ResumeJob(c *gin.Context) {
+	jobID := c.Param("id")
+	if jobID == "" {
+		c.JSON(400, gin.H{"error": "Job ID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	parent, err := a.jobStore.GetJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
+	}
+
+	// Resuming while the original head node still writes to the same work
+	// dir would corrupt the cache, so only finished jobs can be resumed. If
+	// AWS Batch cannot tell us, only trust a stored terminal status.
+	if parent.Status == types.JobStatusQueued {
+		c.JSON(409, gin.H{"error": "Job is still QUEUED, cancel it before resuming"})
+		return
+	}
+	batchJobID, err := a.findBatchJobID(ctx, parent)
+	if err != nil && !parent.IsTerminal() {
+		log.Printf("Error finding batch job of %s: %v", parent.ID, err)
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Cannot check whether job is still running: %v", err)})
+		return
+	}
+	if err == nil {
+		describeOutput, err := a.batchClient.DescribeJobs(ctx, &batch.DescribeJobsInput{
+			Jobs: []string{batchJobID},
+		})
+		if err != nil {
+			log.Printf("Error describing job: %v", err)
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		if len(describeOutput.Jobs) > 0 && !isTerminalStatus(describeOutput.Jobs[0].Status) {
+			c.JSON(409, gin.H{"error": fmt.Sprintf("Job is still %s, cancel it before resuming", describeOutput.Jobs[0].Status)})
+			return
+		}
+	}
+
+	changed := false
+	if parent.SessionID == "" {
+		sessionID, err := services.GetJobLogFile(ctx, a.s3Client, a.logBucket(parent), parent.ID, nfconfig.SessionIDFile)
+		if err != nil {
+			// Without a session ID the head node resumes the latest
+			// session in the restored history, which is the parent's.
+			log.Printf("No session ID recorded for job %s: %v", parent.ID, err)
+		}
+		if sessionID != "" {
+			parent.SessionID = sessionID
+			changed = true
+		}
+	}
+	// The commit is looked up when the job finishes, which can be before the
+	// head node uploaded it, so try again
+	if !nfconfig.IsCommitSHA(parent.CommitID) {
+		if commitID := a.resolveCommitID(ctx, parent); commitID != "" && commitID != parent.CommitID {
+			parent.CommitID = commitID
+			changed = true
+		}
+	}
+	if changed {
+		if err := a.jobStore.PutJob(ctx, *parent); err != nil {
+			log.Printf("Error storing session and commit of job %s: %v", parent.ID, err)
+		}
+	}
+
+	attempt := parent.Derive()
+	attempt.Attempt = max(parent.Attempt, 1) + 1
+	attempt.Resume = true
+	attempt.SessionID = parent.SessionID
+	// Resume the exact code the parent ran, not wherever its branch is now.
+	// An abbreviated commit is only informational, -r needs the full SHA.
+	if nfconfig.IsCommitSHA(parent.CommitID) {
+		attempt.Revision = parent.CommitID
+	} else {
+		log.Printf("Commit of job %s unknown, resuming %s at %q", parent.ID, parent.Repo(), attempt.Revision)
+	}
+
+	result, err := a.submitJob(ctx, &attempt)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Job %s resumed as attempt %d (%s)", parent.ID, attempt.Attempt, attempt.ID)
+
 	c.JSON(200, gin.H{
-		"id":     pJob.ID,
-		"name":   pJob.Name,
-		"status": "SUBMITTED",
-		"arn":    result.JobArn,
+		"id":         attempt.ID,
+		"name":       attempt.Name,
+		"status":     "SUBMITTED",
+		"arn":        result.JobArn,
+		"parent_id":  attempt.ParentID,
+		"attempt":    attempt.Attempt,
+		"session_id": attempt.SessionID,
 	})
 }
 
-// @Summary List all jobs
-// @Description Returns a JSON blob with a list of all jobs
+// @Summary Relaunch a job
+// @Description Submit a copy of a job as a new job, with a JSON merge-patch (RFC 7396) of overrides applied to its spec
 // @Accept  json
 // @Produce json
-// @Success 200 {object} types.Jobs
-// @Router /jobs [get]
-func (a API) ListJobs(c *gin.Context) {
-	// Get queue from query parameter
-	queue := c.Query("queue")
-	if queue == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Queue parameter is required"})
+// @Param   id path string true "Job ID"
+// @Param   overrides body object false "JSON merge-patch applied to the original job spec"
+// @Success 200 {object} map[string]interface{}
+// @Router /jobs/{id}/relaunch [post]
+func (a *API) RelaunchJob(c *gin.Context) {
+	jobID := c.Param("id")
+	if jobID == "" {
+		c.JSON(400, gin.H{"error": "Job ID is required"})
 		return
 	}
 
-	// List jobs from the specified queue for each valid status
-	validStatuses := []batchtypes.JobStatus{
-		batchtypes.JobStatusSubmitted,
-		batchtypes.JobStatusPending,
-		batchtypes.JobStatusRunnable,
-		batchtypes.JobStatusStarting,
-		batchtypes.JobStatusRunning,
-		batchtypes.JobStatusSucceeded,
-		batchtypes.JobStatusFailed,
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Fetch all job specs from S3
-	jobSpecs, err := services.GetJobs(a.s3Client, a.config.JobBucket)
+	original, err := a.jobStore.GetJob(c.Request.Context(), jobID)
 	if err != nil {
-		log.Printf("Error fetching job specs from S3: %v", err)
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
+	}
+
+	spec := *original
+	if len(bytes.TrimSpace(patch)) > 0 {
+		patched, err := services.ApplyJobPatch(*original, patch)
+		if err != nil {
+			log.Printf("Error applying overrides to job %s: %v", jobID, err)
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		spec = *patched
+	}
+	// The patch may not change the identity or run state of the job
+	spec.ID = original.ID
+	relaunch := spec.Derive()
+	if err := a.validateJob(c.Request.Context(), &relaunch); err != nil {
+		log.Printf("Error validating relaunch of job %s: %v", jobID, err)
+		respondError(c, err)
+		return
+	}
+
+	if err := a.launchJob(c.Request.Context(), &relaunch); err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	// Build a map from job name to job spec
-	jobSpecMap := make(map[string]types.Job)
-	for _, spec := range jobSpecs {
-		jobSpecMap[spec.Name] = spec
+	log.Printf("Job %s relaunched as %s", original.ID, relaunch.ID)
+
+	c.JSON(200, gin.H{
+		"id":        relaunch.ID,
+		"name":      relaunch.Name,
+		"status":    relaunch.Status,
+		"arn":       relaunch.BatchJobArn,
+		"parent_id": relaunch.ParentID,
+	})
+}
+
+// @Summary List the attempts of a job
+// @Description Returns the chain of attempts that led to a job, oldest first, by following parent links
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Success 200 {object} types.Jobs
+// @Router /jobs/{id}/attempts [get]
+func (a *API) ListJobAttempts(c *gin.Context) {
+	jobID := c.Param("id")
+	if jobID == "" {
+		c.JSON(400, gin.H{"error": "Job ID is required"})
+		return
 	}
 
-	// Get job IDs for detailed information
-	jobIds := make([]string, 0)
-	for _, status := range validStatuses {
-		listInput := &batch.ListJobsInput{
-			JobQueue:  aws.String(queue),
-			JobStatus: status,
+	chain := make(types.Jobs, 0)
+	seen := make(map[string]bool)
+	for id := jobID; id != "" && !seen[id]; {
+		seen[id] = true
+		job, err := a.jobStore.GetJob(c.Request.Context(), id)
+		if err != nil {
+			if len(chain) == 0 {
+				log.Printf("Error getting job spec: %v", err)
+				c.JSON(404, gin.H{"error": "Job not found"})
+				return
+			}
+			log.Printf("Attempt chain of %s broken at %s: %v", jobID, id, err)
+			break
+		}
+		chain = append(types.Jobs{job.Redacted()}, chain...)
+		id = job.ParentID
+	}
+
+	c.JSON(200, chain)
+}
+
+// logBucket returns the bucket a job's head node uploads its logs to
+func (a *API) logBucket(job *types.Job) string {
+	if job != nil && job.LogBucket != "" {
+		return strings.TrimPrefix(job.LogBucket, "s3://")
+	}
+	return a.config.LogBucket
+}
+
+// JobList is a page of jobs
+type JobList struct {
+	Jobs       []JobWithStatus `json:"jobs"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Total      int             `json:"total"`
+}
+
+const (
+	defaultJobListLimit = 50
+	maxJobListLimit     = 500
+)
+
+// jobListCursor is the position in a listing, handed to clients as an
+// opaque token: the sort key and ID of the last job of the previous page.
+// Jobs created between requests therefore do not shift later pages.
+type jobListCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"id"`
+}
+
+// sortTime formats times so they sort as strings
+const sortTime = "2006-01-02T15:04:05.000000000"
+
+// jobSortKeys are the keys jobs are ordered by for the fields accepted in
+// the sort parameter
+var jobSortKeys = map[string]func(job JobWithStatus) string{
+	"created_at": func(job JobWithStatus) string { return job.CreatedAt.UTC().Format(sortTime) },
+	"updated_at": func(job JobWithStatus) string { return job.UpdatedAt.UTC().Format(sortTime) },
+	"started_at": func(job JobWithStatus) string { return job.StartedAt.UTC().Format(sortTime) },
+	"name":       func(job JobWithStatus) string { return job.Name },
+	"status":     func(job JobWithStatus) string { return job.Status },
+	"pipeline":   func(job JobWithStatus) string { return job.Pipeline },
+}
+
+// jobListing is how ListJobs filters, sorts and pages the stored jobs
+// matching its query
+type jobListing struct {
+	queue      string
+	name       string
+	sort       string
+	descending bool
+	limit      int
+	after      *jobListCursor
+}
+
+// parseJobListing reads the query parameters of ListJobs
+func parseJobListing(c *gin.Context) (services.JobQuery, jobListing, error) {
+	query := services.JobQuery{
+		Status:   c.Query("status"),
+		Pipeline: c.Query("pipeline"),
+		User:     c.Query("user"),
+		GroupID:  c.Query("group_id"),
+	}
+	for param, bound := range map[string]*time.Time{
+		"created_after":  &query.CreatedAfter,
+		"created_before": &query.CreatedBefore,
+	} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, jobListing{}, fmt.Errorf("%s must be an RFC 3339 timestamp", param)
+			}
+			*bound = t
+		}
+	}
+
+	listing := jobListing{
+		queue: c.Query("queue"),
+		name:  strings.ToLower(c.Query("name")),
+		limit: defaultJobListLimit,
+	}
+	sortField := c.DefaultQuery("sort", "-created_at")
+	listing.descending = strings.HasPrefix(sortField, "-")
+	listing.sort = strings.TrimPrefix(sortField, "-")
+	if _, ok := jobSortKeys[listing.sort]; !ok {
+		return query, jobListing{}, fmt.Errorf("Cannot sort by %s", sortField)
+	}
+
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxJobListLimit {
+			return query, jobListing{}, fmt.Errorf("limit must be between 1 and %d", maxJobListLimit)
+		}
+		listing.limit = n
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		var cursor jobListCursor
+		if err := decodeCursor(value, &cursor); err != nil || cursor.Sort != sortField {
+			return query, jobListing{}, errors.New("Invalid cursor")
+		}
+		listing.after = &cursor
+	}
+	return query, listing, nil
+}
+
+// list filters the stored jobs, sorts them by the requested field with ties
+// broken by ID, and returns the page after the cursor
+func (l jobListing) list(specs types.Jobs) JobList {
+	key := jobSortKeys[l.sort]
+	type keyedJob struct {
+		key string
+		job JobWithStatus
+	}
+	jobs := make([]keyedJob, 0)
+	for _, spec := range specs {
+		if l.queue != "" {
+			inQueue := spec.HeadNodeQueue == l.queue
+			if spec.Batch != nil && spec.Batch.JobQueue == l.queue {
+				inQueue = true
+			}
+			if !inQueue {
+				continue
+			}
 		}
-
-		log.Printf("Listing jobs with status %s from queue: %s", status, queue)
-		listOutput, err := a.batchClient.ListJobs(context.TODO(), listInput)
-		if err != nil {
-			log.Printf("Error listing jobs with status %s from queue %s: %v", status, queue, err)
+		if l.name != "" && !strings.Contains(strings.ToLower(spec.Name), l.name) {
 			continue
 		}
+		job := newJobWithStatus(spec)
+		jobs = append(jobs, keyedJob{key: key(job), job: job})
+	}
 
-		log.Printf("Found %d jobs with status %s in queue %s", len(listOutput.JobSummaryList), status, queue)
-		for _, job := range listOutput.JobSummaryList {
-			jobIds = append(jobIds, *job.JobId)
-			log.Printf("Found job: %s (Name: %s, Status: %s)", *job.JobId, *job.JobName, job.Status)
+	// before reports whether a job with key k and ID id comes before another
+	before := func(k, id string, other keyedJob) bool {
+		if k != other.key {
+			return (k < other.key) != l.descending
 		}
+		return id < other.job.ID
 	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return before(jobs[i].key, jobs[i].job.ID, jobs[j])
+	})
 
-	// Describe jobs in batches of 100 (AWS Batch limit)
-	jobsWithStatus := make([]JobWithStatus, 0)
-	for i := 0; i < len(jobIds); i += 100 {
-		end := i + 100
-		if end > len(jobIds) {
-			end = len(jobIds)
+	list := JobList{
+		Jobs:  make([]JobWithStatus, 0),
+		Total: len(jobs),
+	}
+	start := 0
+	if l.after != nil {
+		start = sort.Search(len(jobs), func(i int) bool {
+			return before(l.after.Key, l.after.ID, jobs[i])
+		})
+	}
+	end := min(start+l.limit, len(jobs))
+	for _, job := range jobs[start:end] {
+		list.Jobs = append(list.Jobs, job.job)
+	}
+	if end < len(jobs) && end > start {
+		last := jobs[end-1]
+		sortField := l.sort
+		if l.descending {
+			sortField = "-" + sortField
 		}
+		list.NextCursor = encodeCursor(jobListCursor{Sort: sortField, Key: last.key, ID: last.job.ID})
+	}
+	return list
+}
 
-		describeInput := &batch.DescribeJobsInput{
-			Jobs: jobIds[i:end],
-		}
-		describeOutput, err := a.batchClient.DescribeJobs(context.TODO(), describeInput)
-		if err != nil {
-			log.Printf("Error describing jobs: %v", err)
-			continue
-		}
+// @Summary List all jobs
+// @Description Returns a page of jobs, filtered and sorted on the server
+// @Accept  json
+// @Produce json
+// @Param   queue query string false "Head node queue name or ARN"
+// @Param   status query string false "Job status, e.g. RUNNING"
+// @Param   pipeline query string false "Pipeline name"
+// @Param   user query string false "User who submitted the job"
+// @Param   group_id query string false "Job group ID"
+// @Param   name query string false "Case-insensitive substring of the job name"
+// @Param   created_after query string false "RFC 3339 timestamp"
+// @Param   created_before query string false "RFC 3339 timestamp"
+// @Param   sort query string false "Sort field, prefixed with - for descending (default -created_at)"
+// @Param   limit query int false "Page size (default 50, max 500)"
+// @Param   cursor query string false "next_cursor of the previous page, listed with the same sort"
+// @Success 200 {object} JobList
+// @Router /jobs [get]
+func (a API) ListJobs(c *gin.Context) {
+	query, listing, err := parseJobListing(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-		for _, job := range describeOutput.Jobs {
-			// Convert timestamps from milliseconds to time.Time
-			createdAt := time.Time{}
-			if job.CreatedAt != nil {
-				createdAt = time.UnixMilli(*job.CreatedAt)
-			}
-			startedAt := time.Time{}
-			if job.StartedAt != nil {
-				startedAt = time.UnixMilli(*job.StartedAt)
-			}
-			stoppedAt := time.Time{}
-			if job.StoppedAt != nil {
-				stoppedAt = time.UnixMilli(*job.StoppedAt)
-			}
+	// Job states are kept up to date by the reconciler, so the store is
+	// the source of truth here rather than AWS Batch
+	jobSpecs, err := a.jobStore.QueryJobs(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("Error fetching job specs: %v", err)
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
 
-			// Get exit code if available
-			exitCode := int32(0)
-			if job.Container != nil && job.Container.ExitCode != nil {
-				exitCode = *job.Container.ExitCode
-			}
+	list := listing.list(jobSpecs)
+	log.Printf("Listing %d of %d jobs", len(list.Jobs), list.Total)
 
-			// Get status reason if available
-			statusReason := ""
-			if job.StatusReason != nil {
-				statusReason = *job.StatusReason
-			}
+	c.JSON(200, list)
+}
 
-			// Get job details
-			jobDefinition := ""
-			if job.JobDefinition != nil {
-				jobDefinition = *job.JobDefinition
-			}
+// encodeCursor turns a listing position into an opaque token
+func encodeCursor(cursor interface{}) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
 
-			jobQueue := ""
-			if job.JobQueue != nil {
-				jobQueue = *job.JobQueue
-			}
+// decodeCursor reads a token produced by encodeCursor
+func decodeCursor(token string, cursor interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, cursor)
+}
 
-			attempts := int32(0)
-			if job.Attempts != nil {
-				attempts = int32(len(job.Attempts))
-			}
+// newBatchState captures the AWS Batch description of a job's head node
+func newBatchState(job batchtypes.JobDetail) types.BatchState {
+	state := types.BatchState{
+		JobQueue:      aws.ToString(job.JobQueue),
+		JobDefinition: aws.ToString(job.JobDefinition),
+		StatusReason:  aws.ToString(job.StatusReason),
+		Attempts:      int32(len(job.Attempts)),
+	}
 
-			containerReason := ""
-			if job.Container != nil && job.Container.Reason != nil {
-				containerReason = *job.Container.Reason
-			}
+	// Convert timestamps from milliseconds to time.Time
+	if job.CreatedAt != nil {
+		state.CreatedAt = time.UnixMilli(*job.CreatedAt).UTC()
+	}
+	if job.StartedAt != nil {
+		state.StartedAt = time.UnixMilli(*job.StartedAt).UTC()
+	}
+	if job.StoppedAt != nil {
+		state.StoppedAt = time.UnixMilli(*job.StoppedAt).UTC()
+	}
 
-			logStreamName := ""
-			if job.Container != nil && job.Container.LogStreamName != nil {
-				logStreamName = *job.Container.LogStreamName
-			}
+	if job.Container != nil {
+		if job.Container.ExitCode != nil {
+			state.ExitCode = *job.Container.ExitCode
+		}
+		state.ContainerReason = aws.ToString(job.Container.Reason)
+		state.LogStreamName = aws.ToString(job.Container.LogStreamName)
 
-			// Calculate duration in seconds
-			duration := int64(0)
-			if !startedAt.IsZero() && !stoppedAt.IsZero() {
-				duration = int64(stoppedAt.Sub(startedAt).Seconds())
+		// Get resource requirements
+		for _, req := range job.Container.ResourceRequirements {
+			if req.Value == nil {
+				continue
 			}
-
-			// Get resource requirements
-			memory := int32(0)
-			vcpus := int32(0)
-			if job.Container != nil && job.Container.ResourceRequirements != nil {
-				for _, req := range job.Container.ResourceRequirements {
-					if req.Type == batchtypes.ResourceTypeMemory && req.Value != nil {
-						if value, err := strconv.ParseInt(*req.Value, 10, 32); err == nil {
-							memory = int32(value)
-						}
-					}
-					if req.Type == batchtypes.ResourceTypeVcpu && req.Value != nil {
-						if value, err := strconv.ParseInt(*req.Value, 10, 32); err == nil {
-							vcpus = int32(value)
-						}
-					}
-				}
+			value, err := strconv.ParseInt(*req.Value, 10, 32)
+			if err != nil {
+				continue
 			}
-
-			// Find the job spec by name
-			jobSpec, ok := jobSpecMap[*job.JobName]
-			jobID := *job.JobId // fallback to Batch job ID if not found
-			if ok {
-				jobID = jobSpec.ID
+			switch req.Type {
+			case batchtypes.ResourceTypeMemory:
+				state.Memory = int32(value)
+			case batchtypes.ResourceTypeVcpu:
+				state.Vcpus = int32(value)
 			}
+		}
+	}
+	return state
+}
 
-			jobWithStatus := JobWithStatus{
-				Job: types.Job{
-					ID:   jobID,
-					Name: *job.JobName,
-				},
-				Status:          string(job.Status),
-				CreatedAt:       createdAt,
-				StartedAt:       startedAt,
-				StoppedAt:       stoppedAt,
-				ExitCode:        exitCode,
-				StatusReason:    statusReason,
-				BatchJobId:      *job.JobId,
-				JobDefinition:   jobDefinition,
-				JobQueue:        jobQueue,
-				Attempts:        attempts,
-				ContainerReason: containerReason,
-				LogStreamName:   logStreamName,
-				Duration:        duration,
-				Memory:          memory,
-				Vcpus:           vcpus,
-			}
+// newJobWithStatus flattens a stored job and the last observed AWS Batch
+// state of its head node
+func newJobWithStatus(spec types.Job) JobWithStatus {
+	jobWithStatus := JobWithStatus{
+		Job:        spec.Redacted(),
+		Status:     spec.Status,
+		CreatedAt:  spec.CreatedAt,
+		BatchJobId: spec.BatchJobId,
+	}
+	if spec.Batch == nil {
+		return jobWithStatus
+	}
 
-			jobsWithStatus = append(jobsWithStatus, jobWithStatus)
-			log.Printf("Job details - Name: %s, Status: %s, Created: %s, Started: %s, Stopped: %s, ExitCode: %d, Duration: %ds, Memory: %dMB, vCPUs: %d",
-				*job.JobName, job.Status, createdAt.Format(time.RFC3339), startedAt.Format(time.RFC3339), stoppedAt.Format(time.RFC3339), exitCode, duration, memory, vcpus)
-		}
+	state := spec.Batch
+	if jobWithStatus.CreatedAt.IsZero() {
+		jobWithStatus.CreatedAt = state.CreatedAt
 	}
+	jobWithStatus.StartedAt = state.StartedAt
+	jobWithStatus.StoppedAt = state.StoppedAt
+	jobWithStatus.ExitCode = state.ExitCode
+	jobWithStatus.StatusReason = state.StatusReason
+	jobWithStatus.JobDefinition = state.JobDefinition
+	jobWithStatus.JobQueue = state.JobQueue
+	jobWithStatus.Attempts = state.Attempts
+	jobWithStatus.ContainerReason = state.ContainerReason
+	jobWithStatus.LogStreamName = state.LogStreamName
+	jobWithStatus.Memory = state.Memory
+	jobWithStatus.Vcpus = state.Vcpus
 
-	c.JSON(200, jobsWithStatus)
+	// Calculate duration in seconds
+	if !state.StartedAt.IsZero() && !state.StoppedAt.IsZero() {
+		jobWithStatus.Duration = int64(state.StoppedAt.Sub(state.StartedAt).Seconds())
+	}
+	return jobWithStatus
 }
 
 type JobLogs struct {
@@ -346,105 +1053,62 @@ func (a *API) GetJobLogs(c *gin.Context) {
 
 	log.Printf("Fetching logs for job ID: %s", jobID)
 
-	// Try to get job spec from S3
-	jobSpec, err := services.GetJob(a.s3Client, a.config.JobBucket, jobID)
+	ctx := c.Request.Context()
+	jobSpec, err := a.jobStore.GetJob(ctx, jobID)
 	if err != nil {
-		log.Printf("Error getting job spec from S3: %v", err)
-		// Continue with job ID as name, as it might be the actual job name
-	}
-
-	// Use job name from spec if available, otherwise use the ID
-	jobName := jobID
-	if jobSpec != nil {
-		jobName = jobSpec.Name
-		log.Printf("Found job spec in S3, using job name: %s", jobName)
-	}
-
-	// Get job details from AWS Batch
-	listInput := &batch.ListJobsInput{
-		JobQueue: aws.String("launcher-test-on-demand-MM-Batch-JobQueue"),
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
 	}
 
-	// Try all possible job statuses
-	validStatuses := []batchtypes.JobStatus{
-		batchtypes.JobStatusSubmitted,
-		batchtypes.JobStatusPending,
-		batchtypes.JobStatusRunnable,
-		batchtypes.JobStatusStarting,
-		batchtypes.JobStatusRunning,
-		batchtypes.JobStatusSucceeded,
-		batchtypes.JobStatusFailed,
-	}
+	var logs JobLogs
+	logs.Status = jobSpec.Status
+	logs.JobName = jobSpec.Name
+	logs.BatchJobId = jobSpec.BatchJobId
 
-	var foundJob *batchtypes.JobSummary
-	for _, status := range validStatuses {
-		listInput.JobStatus = status
-		listOutput, err := a.batchClient.ListJobs(context.TODO(), listInput)
+	// Prefer the live status, but fall back to the stored one once AWS Batch
+	// has purged the job
+	if batchJobID, err := a.findBatchJobID(ctx, jobSpec); err == nil {
+		describeOutput, err := a.batchClient.DescribeJobs(ctx, &batch.DescribeJobsInput{
+			Jobs: []string{batchJobID},
+		})
 		if err != nil {
-			log.Printf("Error listing jobs with status %s: %v", status, err)
-			continue
-		}
-
-		// Search for the job by name
-		for _, job := range listOutput.JobSummaryList {
-			if *job.JobName == jobName {
-				foundJob = &job
-				log.Printf("Found job with name %s in status %s", jobName, status)
-				break
-			}
+			log.Printf("Error describing job: %v", err)
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
 		}
-		if foundJob != nil {
-			break
+		if len(describeOutput.Jobs) > 0 {
+			jobDetail := describeOutput.Jobs[0]
+			log.Printf("Found job details - Name: %s, Status: %s", *jobDetail.JobName, jobDetail.Status)
+			a.syncJobStatus(ctx, jobSpec, jobDetail)
+			logs.Status = string(jobDetail.Status)
+			logs.BatchJobId = *jobDetail.JobId
 		}
+	} else {
+		log.Printf("No batch job found for %s: %v", jobID, err)
 	}
 
-	if foundJob == nil {
-		log.Printf("Job not found with name: %s", jobName)
-		c.JSON(404, gin.H{"error": "Job not found"})
-		return
-	}
-
-	// Get job details from AWS Batch
-	describeInput := &batch.DescribeJobsInput{
-		Jobs: []string{*foundJob.JobId},
-	}
-
-	describeOutput, err := a.batchClient.DescribeJobs(context.TODO(), describeInput)
-	if err != nil {
-		log.Printf("Error describing job: %v", err)
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
-
-	if len(describeOutput.Jobs) == 0 {
-		log.Printf("No job details found for job ID: %s", *foundJob.JobId)
+	if logs.Status == "" {
+		log.Printf("Job not found: %s", jobID)
 		c.JSON(404, gin.H{"error": "Job not found"})
 		return
 	}
 
-	jobDetail := describeOutput.Jobs[0]
-	log.Printf("Found job details - Name: %s, Status: %s", *jobDetail.JobName, jobDetail.Status)
-
-	var logs JobLogs
-	logs.Status = string(jobDetail.Status)
-	logs.JobName = *jobDetail.JobName
-	logs.BatchJobId = *jobDetail.JobId
-
 	// Try to get Nextflow log from S3 if job is completed
-	if jobDetail.Status == batchtypes.JobStatusSucceeded || jobDetail.Status == batchtypes.JobStatusFailed {
+	if isTerminalStatus(batchtypes.JobStatus(logs.Status)) {
 		logKey := fmt.Sprintf("jobs/%s/nextflow.log", jobID)
 		getObjectInput := &s3.GetObjectInput{
-			Bucket: aws.String(a.config.LogBucket),
+			Bucket: aws.String(a.logBucket(jobSpec)),
 			Key:    aws.String(logKey),
 		}
 
-		result, err := a.s3Client.GetObject(context.TODO(), getObjectInput)
+		result, err := a.s3Client.GetObject(ctx, getObjectInput)
 		if err == nil {
 			defer result.Body.Close()
 			body, err := io.ReadAll(result.Body)
 			if err == nil {
 				logs.NextflowLog = string(body)
-				log.Printf("Successfully retrieved Nextflow log from S3 for job: %s", *jobDetail.JobName)
+				log.Printf("Successfully retrieved Nextflow log from S3 for job: %s", logs.JobName)
 			} else {
 				log.Printf("Error reading S3 log file: %v", err)
 				logs.Message = fmt.Sprintf("Error reading S3 log file: %v", err)
@@ -454,7 +1118,7 @@ func (a *API) GetJobLogs(c *gin.Context) {
 			logs.Message = fmt.Sprintf("Nextflow log not available in S3 yet: %v", err)
 		}
 	} else {
-		log.Printf("Job %s is not completed yet, Nextflow log will be available after completion", *jobDetail.JobName)
+		log.Printf("Job %s is not completed yet, Nextflow log will be available after completion", logs.JobName)
 		logs.Message = "Nextflow log will be available after job completion"
 	}
 
@@ -466,6 +1130,7 @@ func (a *API) GetJobLogs(c *gin.Context) {
 // @Accept  json
 // @Produce json
 // @Param   id path string true "Job ID"
+// @Param   expires query string false "Lifetime of the URL, e.g. 1h (default PRESIGN_EXPIRY, max 168h)"
 // @Success 200 {object} map[string]string
 // @Router /jobs/{id}/log-url [get]
 func (a *API) GetJobLogPresignedURL(c *gin.Context) {
@@ -477,36 +1142,441 @@ func (a *API) GetJobLogPresignedURL(c *gin.Context) {
 
 	log.Printf("Fetching presigned URL for job ID: %s", jobID)
 
-	// Get job logs from S3
-	getObjectInput := &s3.GetObjectInput{
-		Bucket: aws.String(a.config.LogBucket),
-		Key:    aws.String(fmt.Sprintf("jobs/%s/nextflow.log", jobID)),
+	expiry, err := a.presignExpiry(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	job, err := a.jobStore.GetJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
 	}
 
-	result, err := a.s3Client.GetObject(context.TODO(), getObjectInput)
+	// Check the log exists without downloading it
+	bucket := a.logBucket(job)
+	artifact, err := services.GetJobArtifact(ctx, a.s3Client, bucket, job.ID, "nextflow.log")
 	if err != nil {
+		if errors.Is(err, services.ErrArtifactNotFound) {
+			c.JSON(404, gin.H{"error": "Nextflow log not available in S3 yet"})
+			return
+		}
 		log.Printf("Error getting job logs from S3: %v", err)
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	defer result.Body.Close()
 
-	// Get presigned URL for the log file
-	presignClient := s3.NewPresignClient(a.s3Client)
-	presignedURL, err := presignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
-		Bucket: aws.String(a.config.LogBucket),
-		Key:    aws.String(fmt.Sprintf("jobs/%s/nextflow.log", jobID)),
-	})
+	url, err := a.presignArtifact(ctx, bucket, artifact.Key, expiry)
 	if err != nil {
 		log.Printf("Error generating presigned URL: %v", err)
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{"url": presignedURL.URL})
+	c.JSON(200, gin.H{"url": url, "expires_at": time.Now().UTC().Add(expiry)})
+}
+
+// CancelJobRequest describes why and by whom a job is being stopped
+type CancelJobRequest struct {
+	Reason      string `json:"reason" example:"wrong reference genome"`
+	CancelledBy string `json:"cancelled_by" example:"jdoe"`
+	SweepTasks  bool   `json:"sweep_tasks"`
+}
+
+// @Summary Cancel a job
+// @Description Cancel or terminate the head node of a job, depending on its AWS Batch state. Optionally sweeps the task jobs it left on the task queue.
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Param   request body CancelJobRequest false "Cancellation details"
+// @Success 200 {object} types.Job
+// @Router /jobs/{id} [delete]
+// @Router /jobs/{id}/cancel [post]
+func (a *API) CancelJob(c *gin.Context) {
+	jobID := c.Param("id")
+	if jobID == "" {
+		c.JSON(400, gin.H{"error": "Job ID is required"})
+		return
+	}
+
+	req := CancelJobRequest{
+		Reason:      c.Query("reason"),
+		CancelledBy: c.Query("cancelled_by"),
+		SweepTasks:  c.Query("sweep_tasks") == "true",
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("Error binding JSON: %v", err)
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "Cancelled via nextflow launcher"
+	}
+
+	ctx := c.Request.Context()
+	job, err := a.jobStore.GetJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
+	}
+
+	if err := a.cancelJob(ctx, job, req); err != nil {
+		switch {
+		case errors.Is(err, errJobFinished):
+			c.JSON(409, gin.H{"error": fmt.Sprintf("Job already finished with status %s", job.Status)})
+		case errors.Is(err, errBatchJobNotFound):
+			c.JSON(404, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(200, job.Redacted())
+}
+
+var (
+	// errJobFinished is returned when stopping a job that already finished
+	errJobFinished = errors.New("job already finished")
+	// errBatchJobNotFound is returned when a job's head node is not in AWS
+	// Batch
+	errBatchJobNotFound = errors.New("job not found in AWS Batch")
+	// errJobDequeued is returned when a job expected on the queue has
+	// already been submitted or failed
+	errJobDequeued = errors.New("job no longer queued")
+)
+
+// cancelJob stops a job and records the cancellation. Queued jobs never
+// reached AWS Batch and are failed in place, unless the reconciler submits
+// them first.
+func (a *API) cancelJob(ctx context.Context, job *types.Job, req CancelJobRequest) error {
+	cancellation := &types.Cancellation{
+		By:     req.CancelledBy,
+		Reason: req.Reason,
+		At:     time.Now().UTC(),
+	}
+
+	// Jobs that finished without reaching AWS Batch have nothing to stop
+	if job.IsTerminal() && job.BatchJobId == "" {
+		return errJobFinished
+	}
+
+	if job.Status == types.JobStatusQueued {
+		err := a.withQueuedJob(ctx, job.ID, func(queued *types.Job) error {
+			cancellation.Action = "dequeue"
+			queued.RecordStatus(string(batchtypes.JobStatusFailed), req.Reason, cancellation.At)
+			queued.Cancellation = cancellation
+			queued.UpdatedAt = cancellation.At
+			*job = *queued
+			return a.jobStore.PutJob(ctx, *queued)
+		})
+		if !errors.Is(err, errJobDequeued) {
+			if err != nil {
+				log.Printf("Error storing job: %v", err)
+			}
+			return err
+		}
+
+		// The reconciler took it off the queue first
+		current, err := a.jobStore.GetJob(ctx, job.ID)
+		if err != nil {
+			return err
+		}
+		*job = *current
+		if job.IsTerminal() && job.BatchJobId == "" {
+			return errJobFinished
+		}
+	}
+
+	batchJobID, err := a.findBatchJobID(ctx, job)
+	if err != nil {
+		log.Printf("Error finding batch job for %s: %v", job.ID, err)
+		return fmt.Errorf("%w: %v", errBatchJobNotFound, err)
+	}
+
+	describeOutput, err := a.batchClient.DescribeJobs(ctx, &batch.DescribeJobsInput{
+		Jobs: []string{batchJobID},
+	})
+	if err != nil {
+		log.Printf("Error describing job: %v", err)
+		return err
+	}
+	if len(describeOutput.Jobs) == 0 {
+		return errBatchJobNotFound
+	}
+
+	status := describeOutput.Jobs[0].Status
+	if isTerminalStatus(status) {
+		a.syncJobStatus(ctx, job, describeOutput.Jobs[0])
+		return errJobFinished
+	}
+
+	action, err := a.stopBatchJob(ctx, batchJobID, status, req.Reason)
+	if err != nil {
+		log.Printf("Error stopping batch job %s: %v", batchJobID, err)
+		return err
+	}
+	log.Printf("Job %s (%s) stopped with %s", job.ID, batchJobID, action)
+	cancellation.Action = action
+
+	if req.SweepTasks {
+		swept, err := a.sweepTaskJobs(ctx, job, req.Reason)
+		if err != nil {
+			// The head node is already stopping, so report what we have.
+			log.Printf("Error sweeping task jobs for %s: %v", job.ID, err)
+		}
+		cancellation.SweptTasks = swept
+	}
+	recordBatchStatus(job, describeOutput.Jobs[0])
+
+	job.Cancellation = cancellation
+	job.UpdatedAt = cancellation.At
+	if err := a.jobStore.PutJob(ctx, *job); err != nil {
+		log.Printf("Error storing job: %v", err)
+		return err
+	}
+	return nil
+}
+
+// findBatchJobID returns the AWS Batch job ID of a job's head node. Jobs
+// without a recorded ID are looked up by name in their head node queue.
+func (a *API) findBatchJobID(ctx context.Context, job *types.Job) (string, error) {
+	if job.BatchJobId != "" {
+		return job.BatchJobId, nil
+	}
+	if job.HeadNodeQueue == "" {
+		return "", fmt.Errorf("job %s has no batch job id or head node queue", job.ID)
+	}
+
+	listOutput, err := a.batchClient.ListJobs(ctx, &batch.ListJobsInput{
+		JobQueue: aws.String(job.HeadNodeQueue),
+		Filters: []batchtypes.KeyValuesPair{
+			{
+				Name:   aws.String("JOB_NAME"),
+				Values: []string{job.ID},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list jobs in queue %s: %v", job.HeadNodeQueue, err)
+	}
+
+	var latest *batchtypes.JobSummary
+	for i, summary := range listOutput.JobSummaryList {
+		if latest == nil || aws.ToInt64(summary.CreatedAt) > aws.ToInt64(latest.CreatedAt) {
+			latest = &listOutput.JobSummaryList[i]
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("job %s not found in queue %s", job.ID, job.HeadNodeQueue)
+	}
+	return *latest.JobId, nil
+}
+
+// stopBatchJob cancels a job that has not started yet and terminates one
+// that has. It returns the name of the AWS Batch action used.
+func (a *API) stopBatchJob(ctx context.Context, batchJobID string, status batchtypes.JobStatus, reason string) (string, error) {
+	switch status {
+	case batchtypes.JobStatusSubmitted, batchtypes.JobStatusPending, batchtypes.JobStatusRunnable:
+		_, err := a.batchClient.CancelJob(ctx, &batch.CancelJobInput{
+			JobId:  aws.String(batchJobID),
+			Reason: aws.String(reason),
+		})
+		return "CancelJob", err
+	default:
+		_, err := a.batchClient.TerminateJob(ctx, &batch.TerminateJobInput{
+			JobId:  aws.String(batchJobID),
+			Reason: aws.String(reason),
+		})
+		return "TerminateJob", err
+	}
+}
+
+// sweepTaskJobs stops the task jobs the Nextflow head node submitted to the
+// task queue. Tasks are matched on the tag set in the rendered config.
+func (a *API) sweepTaskJobs(ctx context.Context, job *types.Job, reason string) ([]string, error) {
+	if job.TaskQueue == "" {
+		return nil, nil
+	}
+
+	activeStatuses := []batchtypes.JobStatus{
+		batchtypes.JobStatusSubmitted,
+		batchtypes.JobStatusPending,
+		batchtypes.JobStatusRunnable,
+		batchtypes.JobStatusStarting,
+		batchtypes.JobStatusRunning,
+	}
+
+	jobIds := make([]string, 0)
+	for _, status := range activeStatuses {
+		paginator := batch.NewListJobsPaginator(a.batchClient, &batch.ListJobsInput{
+			JobQueue:  aws.String(job.TaskQueue),
+			JobStatus: status,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s jobs in queue %s: %v", status, job.TaskQueue, err)
+			}
+			for _, summary := range page.JobSummaryList {
+				jobIds = append(jobIds, *summary.JobId)
+			}
+		}
+	}
+
+	swept := make([]string, 0)
+	for i := 0; i < len(jobIds); i += 100 {
+		end := i + 100
+		if end > len(jobIds) {
+			end = len(jobIds)
+		}
+
+		describeOutput, err := a.batchClient.DescribeJobs(ctx, &batch.DescribeJobsInput{
+			Jobs: jobIds[i:end],
+		})
+		if err != nil {
+			return swept, fmt.Errorf("failed to describe task jobs: %v", err)
+		}
+
+		for _, task := range describeOutput.Jobs {
+			if task.Tags[nfconfig.TaskJobTag] != job.ID {
+				continue
+			}
+			if _, err := a.stopBatchJob(ctx, *task.JobId, task.Status, reason); err != nil {
+				log.Printf("Error stopping task job %s: %v", *task.JobId, err)
+				continue
+			}
+			swept = append(swept, *task.JobId)
+		}
+	}
+	log.Printf("Swept %d task jobs for job %s from queue %s", len(swept), job.ID, job.TaskQueue)
+	return swept, nil
+}
+
+// syncJobStatus records the AWS Batch status of a job's head node in its
+// status history and stores the job if anything changed. The change is
+// applied to a freshly read record, so a cancellation stored since the
+// caller read the job is not overwritten; job is updated to what was stored.
+func (a *API) syncJobStatus(ctx context.Context, job *types.Job, batchJob batchtypes.JobDetail) bool {
+	if !recordBatchStatus(job, batchJob) {
+		return false
+	}
+
+	current, err := a.jobStore.GetJob(ctx, job.ID)
+	if err != nil {
+		log.Printf("Error getting job %s to store its status: %v", job.ID, err)
+		return false
+	}
+	// The job was submitted again since the caller read it
+	if current.BatchJobId != "" && current.BatchJobId != aws.ToString(batchJob.JobId) {
+		*job = *current
+		return false
+	}
+	if !recordBatchStatus(current, batchJob) {
+		*job = *current
+		return false
+	}
+	if current.IsTerminal() && current.CommitID == "" {
+		current.CommitID = a.resolveCommitID(ctx, current)
+	}
+	if current.IsTerminal() {
+		a.flushProgress(ctx, current.ID)
+	}
+	if err := a.jobStore.PutJob(ctx, *current); err != nil {
+		log.Printf("Error storing status of job %s: %v", job.ID, err)
+		return false
+	}
+	*job = *current
+	return true
+}
+
+// recordBatchStatus applies the state of a Batch job to a job record. Batch
+// only timestamps submission, start and stop; other transitions are dated
+// when they are observed.
+func recordBatchStatus(job *types.Job, batchJob batchtypes.JobDetail) bool {
+	changed := false
+	if job.BatchJobId == "" && batchJob.JobId != nil {
+		job.BatchJobId = *batchJob.JobId
+		changed = true
+	}
+	if job.BatchJobArn == "" && batchJob.JobArn != nil {
+		job.BatchJobArn = *batchJob.JobArn
+		changed = true
+	}
+
+	state := newBatchState(batchJob)
+	if job.Batch == nil || *job.Batch != state {
+		job.Batch = &state
+		changed = true
+	}
+
+	status := string(batchJob.Status)
+	if status == job.Status {
+		return changed
+	}
+
+	at := time.Now().UTC()
+	switch batchJob.Status {
+	case batchtypes.JobStatusSubmitted:
+		if batchJob.CreatedAt != nil {
+			at = time.UnixMilli(*batchJob.CreatedAt).UTC()
+		}
+	case batchtypes.JobStatusRunning:
+		if batchJob.StartedAt != nil {
+			at = time.UnixMilli(*batchJob.StartedAt).UTC()
+		}
+	case batchtypes.JobStatusSucceeded, batchtypes.JobStatusFailed:
+		// Fill in a start we did not get to see
+		if batchJob.StartedAt != nil && job.Status != string(batchtypes.JobStatusRunning) {
+			job.RecordStatus(string(batchtypes.JobStatusRunning), "", time.UnixMilli(*batchJob.StartedAt).UTC())
+		}
+		if batchJob.StoppedAt != nil {
+			at = time.UnixMilli(*batchJob.StoppedAt).UTC()
+		}
+	}
+	job.RecordStatus(status, aws.ToString(batchJob.StatusReason), at)
+	return true
+}
+
+// resolveCommitID reads the commit a finished job ran. The head node records
+// the full SHA; older head nodes only left the abbreviated one in the
+// Nextflow log.
+func (a *API) resolveCommitID(ctx context.Context, job *types.Job) string {
+	commitID, err := services.GetJobLogFile(ctx, a.s3Client, a.logBucket(job), job.ID, nfconfig.CommitIDFile)
+	if err == nil && nfconfig.IsCommitSHA(commitID) {
+		log.Printf("Job %s ran %s at commit %s", job.ID, job.Repo(), commitID)
+		return commitID
+	}
+
+	result, err := a.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(a.logBucket(job)),
+		Key:    aws.String(fmt.Sprintf("jobs/%s/nextflow.log", job.ID)),
+	})
+	if err != nil {
+		log.Printf("Nextflow log of job %s not available, commit unknown: %v", job.ID, err)
+		return ""
+	}
+	defer result.Body.Close()
+
+	commitID = nfconfig.ParseCommitID(result.Body)
+	if commitID != "" {
+		log.Printf("Job %s ran %s at abbreviated commit %s", job.ID, job.Repo(), commitID)
+	}
+	return commitID
+}
+
+// isTerminalStatus reports whether AWS Batch is done with a job
+func isTerminalStatus(status batchtypes.JobStatus) bool {
+	return status == batchtypes.JobStatusSucceeded || status == batchtypes.JobStatusFailed
 }
 
 // GetJob retrieves a job by ID
-func (a *API) GetJob(jobID string) (*types.Job, error) {
-	return services.GetJob(a.s3Client, a.config.JobBucket, jobID)
+func (a *API) GetJob(ctx context.Context, jobID string) (*types.Job, error) {
+	return a.jobStore.GetJob(ctx, jobID)
 }
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// newJobListing parses the listing parameters of a /jobs request
+func newJobListing(t *testing.T, rawQuery string) (jobListing, error) {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/v1/jobs?"+rawQuery, nil)
+	_, listing, err := parseJobListing(c)
+	return listing, err
+}
+
+func jobIDs(list JobList) string {
+	ids := make([]string, 0, len(list.Jobs))
+	for _, job := range list.Jobs {
+		ids = append(ids, job.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestParseJobListing(t *testing.T) {
+	listing, err := newJobListing(t, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if listing.sort != "created_at" || !listing.descending || listing.limit != defaultJobListLimit {
+		t.Errorf("expected newest first in pages of %d, got %+v", defaultJobListLimit, listing)
+	}
+
+	for _, rawQuery := range []string{
+		"sort=size",
+		"limit=0",
+		"limit=501",
+		"created_after=yesterday",
+		"cursor=not-a-cursor",
+		// A cursor is only valid for the sort it was issued for
+		"sort=name&cursor=" + encodeCursor(jobListCursor{Sort: "-created_at", Key: "x", ID: "a"}),
+	} {
+		if _, err := newJobListing(t, rawQuery); err == nil {
+			t.Errorf("expected %q to be rejected", rawQuery)
+		}
+	}
+}
+
+func TestJobListingList(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	jobs := types.Jobs{
+		{ID: "a", Name: "RNA-1", HeadNodeQueue: "head", CreatedAt: day},
+		{ID: "b", Name: "rna-2", HeadNodeQueue: "other", Batch: &types.BatchState{JobQueue: "head"}, CreatedAt: day.Add(time.Hour)},
+		{ID: "c", Name: "dna-1", HeadNodeQueue: "other", CreatedAt: day.Add(time.Hour)},
+		{ID: "d", Name: "rna-3", HeadNodeQueue: "head", CreatedAt: day.Add(2 * time.Hour)},
+	}
+
+	tests := []struct {
+		rawQuery string
+		want     string
+	}{
+		{"", "d,b,c,a"},
+		{"sort=created_at", "a,b,c,d"},
+		{"sort=name", "a,c,b,d"},
+		{"queue=head", "d,b,a"},
+		{"name=RNA", "d,b,a"},
+		{"queue=head&name=rna-1", "a"},
+		{"limit=2", "d,b"},
+	}
+	for _, tt := range tests {
+		listing, err := newJobListing(t, tt.rawQuery)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.rawQuery, err)
+		}
+		if got := jobIDs(listing.list(jobs)); got != tt.want {
+			t.Errorf("%q: listed %s, want %s", tt.rawQuery, got, tt.want)
+		}
+	}
+}
+
+func TestJobListingCursor(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	jobs := types.Jobs{
+		{ID: "a", CreatedAt: day},
+		{ID: "b", CreatedAt: day.Add(time.Hour)},
+		{ID: "c", CreatedAt: day.Add(time.Hour)},
+		{ID: "d", CreatedAt: day.Add(2 * time.Hour)},
+		{ID: "e", CreatedAt: day.Add(3 * time.Hour)},
+	}
+
+	listing, err := newJobListing(t, "limit=2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	page := listing.list(jobs)
+	if jobIDs(page) != "e,d" || page.NextCursor == "" || page.Total != 5 {
+		t.Fatalf("unexpected first page %s, cursor %q, total %d", jobIDs(page), page.NextCursor, page.Total)
+	}
+
+	// A job created before the next page is requested does not shift it
+	jobs = append(jobs, types.Job{ID: "f", CreatedAt: day.Add(4 * time.Hour)})
+	var pages []string
+	for page.NextCursor != "" {
+		listing, err := newJobListing(t, "limit=2&cursor="+page.NextCursor)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		page = listing.list(jobs)
+		pages = append(pages, jobIDs(page))
+	}
+	if got := strings.Join(pages, " "); got != "b,c a" {
+		t.Errorf("expected the remaining pages b,c and a, got %q", got)
+	}
+}
@@ -0,0 +1,389 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	batchtypes "github.com/aws/aws-sdk-go-v2/service/batch/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// jobGroupValidationWorkers bounds the number of child jobs validated at
+// once when a group is created
+const jobGroupValidationWorkers = 8
+
+// JobGroupStatus is a job group with the aggregate status of its children
+type JobGroupStatus struct {
+	types.JobGroup
+	Status string           `json:"status" example:"RUNNING"`
+	Counts map[string]int   `json:"counts"`
+	Jobs   []JobGroupMember `json:"jobs,omitempty"`
+}
+
+// JobGroupMember is a child job of a group
+type JobGroupMember struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Status     string                 `json:"status"`
+	BatchJobId string                 `json:"batch_job_id,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// newJobGroupStatus aggregates the status of a group's children. A group
+// is RUNNING while any child runs, PENDING while children wait to run or
+// before any are stored, and once every child finished SUCCEEDED only if
+// all of them succeeded.
+func newJobGroupStatus(group types.JobGroup, children types.Jobs, withJobs bool) JobGroupStatus {
+	status := JobGroupStatus{
+		JobGroup: group.Redacted(),
+		Counts:   make(map[string]int),
+	}
+	finished := 0
+	for _, child := range children {
+		status.Counts[child.Status]++
+		if child.IsTerminal() {
+			finished++
+		}
+		if withJobs {
+			status.Jobs = append(status.Jobs, JobGroupMember{
+				ID:         child.ID,
+				Name:       child.Name,
+				Status:     child.Status,
+				BatchJobId: child.BatchJobId,
+				Parameters: child.Parameters,
+			})
+		}
+	}
+
+	switch {
+	case status.Counts[string(batchtypes.JobStatusRunning)] > 0:
+		status.Status = string(batchtypes.JobStatusRunning)
+	case finished < len(children), len(children) == 0:
+		status.Status = string(batchtypes.JobStatusPending)
+	case status.Counts[string(batchtypes.JobStatusSucceeded)] == len(children):
+		status.Status = string(batchtypes.JobStatusSucceeded)
+	default:
+		status.Status = string(batchtypes.JobStatusFailed)
+	}
+	if withJobs {
+		sort.Slice(status.Jobs, func(i, j int) bool {
+			return status.Jobs[i].Name < status.Jobs[j].Name
+		})
+	}
+	return status
+}
+
+// @Summary Create a job group
+// @Description Launch one job per override set, or one per combination of swept parameter values, from a template job. At most concurrency children run at once; the rest are queued and submitted by the reconciler as slots free up.
+// @Accept  json
+// @Produce json
+// @Param   group body types.JobGroup true "Job group"
+// @Success 201 {object} JobGroupStatus
+// @Failure 422 {object} map[string]interface{}
+// @Router /job-groups [post]
+func (a *API) CreateJobGroup(c *gin.Context) {
+	var group types.JobGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	group.ID = uuid.New().String()
+	children, err := services.ExpandJobGroup(group)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	a.respondJobGroup(c, &group, children)
+}
+
+// @Summary List job groups
+// @Description Returns every job group with the aggregate status of its children
+// @Accept  json
+// @Produce json
+// @Success 200 {array} JobGroupStatus
+// @Router /job-groups [get]
+func (a *API) ListJobGroups(c *gin.Context) {
+	ctx := c.Request.Context()
+	groups, err := services.GetJobGroups(ctx, a.s3Client, a.config.JobBucket)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	jobs, err := a.jobStore.GetJobs(ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	children := make(map[string]types.Jobs)
+	for _, job := range jobs {
+		if job.GroupID != "" {
+			children[job.GroupID] = append(children[job.GroupID], job)
+		}
+	}
+	statuses := make([]JobGroupStatus, 0, len(groups))
+	for _, group := range groups {
+		statuses = append(statuses, newJobGroupStatus(group, children[group.ID], false))
+	}
+	c.JSON(200, statuses)
+}
+
+// @Summary Get a job group
+// @Description Returns a job group with the aggregate status of its children and the children themselves
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Job group ID"
+// @Success 200 {object} JobGroupStatus
+// @Router /job-groups/{id} [get]
+func (a *API) GetJobGroup(c *gin.Context) {
+	ctx := c.Request.Context()
+	group, children, err := a.getJobGroup(ctx, c.Param("id"))
+	if err != nil {
+		respondJobGroupError(c, err)
+		return
+	}
+	c.JSON(200, newJobGroupStatus(*group, children, true))
+}
+
+// @Summary Cancel a job group
+// @Description Cancel every unfinished child of a job group, dequeuing the ones that have not been submitted yet
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Job group ID"
+// @Param   request body CancelJobRequest false "Cancellation details"
+// @Success 200 {object} JobGroupStatus
+// @Router /job-groups/{id}/cancel [post]
+func (a *API) CancelJobGroup(c *gin.Context) {
+	req := CancelJobRequest{
+		Reason:      c.Query("reason"),
+		CancelledBy: c.Query("cancelled_by"),
+		SweepTasks:  c.Query("sweep_tasks") == "true",
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("Error binding JSON: %v", err)
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "Job group cancelled via nextflow launcher"
+	}
+
+	ctx := c.Request.Context()
+	group, children, err := a.getJobGroup(ctx, c.Param("id"))
+	if err != nil {
+		respondJobGroupError(c, err)
+		return
+	}
+
+	// Dequeue waiting children first so the reconciler cannot submit them
+	// while the running ones are being stopped
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].Status == types.JobStatusQueued && children[j].Status != types.JobStatusQueued
+	})
+	cancelled := 0
+	for i := range children {
+		child := &children[i]
+		if child.IsTerminal() {
+			continue
+		}
+		if err := a.cancelJob(ctx, child, req); err != nil {
+			if !errors.Is(err, errJobFinished) {
+				log.Printf("Error cancelling job %s of group %s: %v", child.ID, group.ID, err)
+			}
+			continue
+		}
+		cancelled++
+	}
+	log.Printf("Cancelled %d jobs of group %s", cancelled, group.ID)
+
+	c.JSON(200, newJobGroupStatus(*group, children, true))
+}
+
+// @Summary Relaunch a job group
+// @Description Relaunch the children of a job group as a new group, optionally only the failed ones. The body is a JSON merge patch applied to every child.
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Job group ID"
+// @Param   failed_only query bool false "Only relaunch children that failed"
+// @Param   overrides body object false "JSON merge patch applied to every child"
+// @Success 201 {object} JobGroupStatus
+// @Failure 422 {object} map[string]interface{}
+// @Router /job-groups/{id}/relaunch [post]
+func (a *API) RelaunchJobGroup(c *gin.Context) {
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	failedOnly := c.Query("failed_only") == "true"
+
+	original, children, err := a.getJobGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondJobGroupError(c, err)
+		return
+	}
+
+	group := *original
+	group.ID = uuid.New().String()
+	group.ParentID = original.ID
+	if len(bytes.TrimSpace(patch)) > 0 {
+		template, err := services.ApplyJobPatch(group.Template, patch)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		group.Template = *template
+	}
+
+	relaunches := make([]types.Job, 0, len(children))
+	for _, child := range children {
+		if failedOnly && child.Status != string(batchtypes.JobStatusFailed) {
+			continue
+		}
+		spec := child
+		if len(bytes.TrimSpace(patch)) > 0 {
+			patched, err := services.ApplyJobPatch(child, patch)
+			if err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			spec = *patched
+			// The patch may not change the identity or run state of the job
+			spec.ID = child.ID
+		}
+		relaunch := spec.Derive()
+		relaunch.GroupID = group.ID
+		relaunches = append(relaunches, relaunch)
+	}
+	if len(relaunches) == 0 {
+		c.JSON(409, gin.H{"error": "No jobs in the group to relaunch"})
+		return
+	}
+	a.respondJobGroup(c, &group, relaunches)
+}
+
+// respondJobGroup launches a new group and responds with its status
+func (a *API) respondJobGroup(c *gin.Context, group *types.JobGroup, children []types.Job) {
+	if err := a.launchJobGroup(c.Request.Context(), group, children); err != nil {
+		log.Printf("Error launching job group: %v", err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(201, newJobGroupStatus(*group, children, true))
+}
+
+// launchJobGroup validates every child of a new group, stores the group
+// and submits as many children as its concurrency allows, queueing the
+// rest. Nothing is stored unless every child is valid.
+func (a *API) launchJobGroup(ctx context.Context, group *types.JobGroup, children []types.Job) error {
+	var errs types.ValidationErrors
+	if group.Concurrency < 0 {
+		errs.Add("concurrency", "must not be negative")
+	}
+	if group.Concurrency == 0 {
+		group.Concurrency = types.DefaultJobGroupConcurrency
+	}
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var failed error
+	sem := make(chan struct{}, jobGroupValidationWorkers)
+	for i := range children {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := a.validateJob(ctx, &children[i])
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			var childErrs types.ValidationErrors
+			if !errors.As(prefixFieldErrors(err, fmt.Sprintf("jobs[%d].", i)), &childErrs) {
+				failed = err
+				return
+			}
+			errs = append(errs, childErrs...)
+		}(i)
+	}
+	wg.Wait()
+	if failed != nil {
+		return failed
+	}
+	if err := errs.Err(); err != nil {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+		return errs
+	}
+
+	now := time.Now().UTC()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+	group.JobIDs = make([]string, len(children))
+	for i := range children {
+		children[i].ID = uuid.New().String()
+		children[i].GroupID = group.ID
+		// Keep the children in order when the reconciler releases them
+		children[i].CreatedAt = now.Add(time.Duration(i) * time.Microsecond)
+		group.JobIDs[i] = children[i].ID
+	}
+	if err := services.PutJobGroup(ctx, a.s3Client, a.config.JobBucket, *group); err != nil {
+		return err
+	}
+
+	for i := range children {
+		if i < group.Concurrency {
+			err := a.launchJob(ctx, &children[i])
+			if err == nil {
+				continue
+			}
+			// Leave it to the reconciler to retry
+			log.Printf("Error launching job %s of group %s, queueing it: %v", children[i].ID, group.ID, err)
+		}
+		if err := a.queueJob(ctx, &children[i], "Waiting for a free slot in job group "+group.ID); err != nil {
+			log.Printf("Error queueing job %s of group %s: %v", children[i].ID, group.ID, err)
+		}
+	}
+	log.Printf("Launched job group %s with %d jobs, at most %d at once", group.ID, len(children), group.Concurrency)
+	return nil
+}
+
+// getJobGroup retrieves a job group and its children
+func (a *API) getJobGroup(ctx context.Context, id string) (*types.JobGroup, types.Jobs, error) {
+	group, err := services.GetJobGroup(ctx, a.s3Client, a.config.JobBucket, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	children, err := a.jobStore.QueryJobs(ctx, services.JobQuery{GroupID: id})
+	if err != nil {
+		return nil, nil, err
+	}
+	return group, children, nil
+}
+
+// respondJobGroupError maps job group lookup errors to responses
+func respondJobGroupError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrJobGroupNotFound) {
+		c.JSON(404, gin.H{"error": "Job group not found"})
+		return
+	}
+	c.JSON(500, gin.H{"error": err.Error()})
+}
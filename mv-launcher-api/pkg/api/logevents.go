@@ -0,0 +1,194 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLogEventsLimit = 1000
+	maxLogEventsLimit     = 10000
+)
+
+// LogEvent is a single line of a head node's CloudWatch log
+type LogEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+}
+
+// LogEvents is a page of a head node attempt's CloudWatch log
+type LogEvents struct {
+	Attempt       int        `json:"attempt" example:"1"`
+	Attempts      int        `json:"attempts" example:"2"`
+	LogStreamName string     `json:"log_stream_name"`
+	Events        []LogEvent `json:"events"`
+	NextToken     string     `json:"next_token,omitempty"`
+}
+
+// @Summary Get job log events
+// @Description Returns a page of the CloudWatch log of one AWS Batch attempt of a job's head node, oldest first. Unlike nextflow.log this is available while the job runs and when the container dies before uploading its log.
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Param   attempt query int false "Attempt number, from 1 (default latest)"
+// @Param   start_time query string false "RFC 3339 timestamp"
+// @Param   end_time query string false "RFC 3339 timestamp"
+// @Param   filter query string false "Only return lines containing this text"
+// @Param   limit query int false "Page size (default 1000, max 10000)"
+// @Param   next_token query string false "next_token of the previous page"
+// @Success 200 {object} LogEvents
+// @Router /jobs/{id}/logs/events [get]
+func (a *API) GetJobLogEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	job, err := a.jobStore.GetJob(ctx, c.Param("id"))
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
+	}
+
+	var startTime, endTime *int64
+	for param, bound := range map[string]**int64{
+		"start_time": &startTime,
+		"end_time":   &endTime,
+	} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be an RFC 3339 timestamp", param)})
+				return
+			}
+			*bound = aws.Int64(t.UnixMilli())
+		}
+	}
+
+	limit := defaultLogEventsLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxLogEventsLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLogEventsLimit)})
+			return
+		}
+		limit = n
+	}
+
+	// AWS Batch keeps every attempt's log stream; once it has purged the
+	// job, only the last observed stream is known
+	var streams []string
+	batchJob, err := a.describeHeadNode(ctx, job)
+	if err != nil {
+		log.Printf("Error describing head node of job %s: %v", job.ID, err)
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if batchJob != nil {
+		for _, attempt := range newJobAttempts(*batchJob) {
+			streams = append(streams, attempt.LogStreamName)
+		}
+	} else if job.Batch != nil && job.Batch.LogStreamName != "" {
+		streams = append(streams, job.Batch.LogStreamName)
+	}
+	if len(streams) == 0 {
+		c.JSON(404, gin.H{"error": "Job has not started an attempt yet"})
+		return
+	}
+
+	attempt := len(streams)
+	if value := c.Query("attempt"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > len(streams) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("attempt must be between 1 and %d", len(streams))})
+			return
+		}
+		attempt = n
+	}
+	stream := streams[attempt-1]
+	if stream == "" {
+		c.JSON(404, gin.H{"error": fmt.Sprintf("Attempt %d has no log stream", attempt)})
+		return
+	}
+
+	page := LogEvents{
+		Attempt:       attempt,
+		Attempts:      len(streams),
+		LogStreamName: stream,
+		Events:        make([]LogEvent, 0),
+	}
+	nextToken := c.Query("next_token")
+
+	var notFound *logstypes.ResourceNotFoundException
+	if filter := c.Query("filter"); filter != "" {
+		output, err := a.logsClient.FilterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName:   aws.String(a.config.BatchLogGroup),
+			LogStreamNames: []string{stream},
+			FilterPattern:  aws.String(strconv.Quote(filter)),
+			StartTime:      startTime,
+			EndTime:        endTime,
+			Limit:          aws.Int32(int32(limit)),
+			NextToken:      optionalString(nextToken),
+		})
+		if err != nil && !errors.As(err, &notFound) {
+			log.Printf("Error filtering log events of job %s: %v", job.ID, err)
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		if output != nil {
+			for _, event := range output.Events {
+				page.Events = append(page.Events, newLogEvent(event.Timestamp, event.Message))
+			}
+			page.NextToken = aws.ToString(output.NextToken)
+		}
+	} else {
+		output, err := a.logsClient.GetLogEvents(ctx, &cloudwatchlogs.GetLogEventsInput{
+			LogGroupName:  aws.String(a.config.BatchLogGroup),
+			LogStreamName: aws.String(stream),
+			StartFromHead: aws.Bool(true),
+			StartTime:     startTime,
+			EndTime:       endTime,
+			Limit:         aws.Int32(int32(limit)),
+			NextToken:     optionalString(nextToken),
+		})
+		if err != nil && !errors.As(err, &notFound) {
+			log.Printf("Error getting log events of job %s: %v", job.ID, err)
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		if output != nil {
+			for _, event := range output.Events {
+				page.Events = append(page.Events, newLogEvent(event.Timestamp, event.Message))
+			}
+			// The forward token comes back unchanged at the end of the stream.
+			// A page can be empty before that when a time range skips events.
+			if token := aws.ToString(output.NextForwardToken); token != nextToken {
+				page.NextToken = token
+			}
+		}
+	}
+
+	c.JSON(200, page)
+}
+
+func newLogEvent(timestamp *int64, message *string) LogEvent {
+	return LogEvent{
+		Timestamp: time.UnixMilli(aws.ToInt64(timestamp)).UTC(),
+		Message:   strings.TrimRight(aws.ToString(message), "\n"),
+	}
+}
+
+// optionalString returns nil for an empty string, as AWS expects for
+// omitted tokens
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/batch"
+	batchtypes "github.com/aws/aws-sdk-go-v2/service/batch/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// logStreamPollInterval is how often a streamed log is checked for new
+	// events
+	logStreamPollInterval = 2 * time.Second
+	// logStreamStatusInterval is how often the head node's state is
+	// refreshed while its log is streamed
+	logStreamStatusInterval = 10 * time.Second
+	// logStreamHeartbeat is how long a stream may stay silent before a
+	// comment is sent to keep proxies from closing it
+	logStreamHeartbeat = 15 * time.Second
+)
+
+// logCursor is the position of the last streamed log event: the n-th event
+// (from 0) with timestamp ts. It is sent as the SSE event ID "ts:n".
+type logCursor struct {
+	ts int64
+	n  int
+}
+
+func (c logCursor) String() string {
+	return fmt.Sprintf("%d:%d", c.ts, c.n)
+}
+
+// parseLogCursor reads an SSE event ID written by logCursor.String
+func parseLogCursor(id string) (logCursor, error) {
+	ts, n, ok := strings.Cut(id, ":")
+	if !ok {
+		return logCursor{}, fmt.Errorf("event ID must be <timestamp>:<index>, got %q", id)
+	}
+	cursor := logCursor{}
+	var err error
+	if cursor.ts, err = strconv.ParseInt(ts, 10, 64); err != nil {
+		return logCursor{}, fmt.Errorf("invalid event ID timestamp %q", ts)
+	}
+	if cursor.n, err = strconv.Atoi(n); err != nil || cursor.n < 0 {
+		return logCursor{}, fmt.Errorf("invalid event ID index %q", n)
+	}
+	return cursor, nil
+}
+
+// advance moves the cursor to an event with timestamp ts and reports
+// whether the event is new. skip is the number of events at the cursor's
+// timestamp that were already sent before a reconnect and are passed over.
+func (c *logCursor) advance(ts int64, skip *int) bool {
+	if ts < c.ts {
+		return false
+	}
+	if ts == c.ts && *skip > 0 {
+		*skip--
+		return false
+	}
+	if ts == c.ts {
+		c.n++
+	} else {
+		*c = logCursor{ts: ts}
+	}
+	return true
+}
+
+// writeSSE writes a server-sent event. Multi-line data is split over
+// several data fields.
+func writeSSE(w io.Writer, id, event, data string) error {
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(strings.TrimRight(data, "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// describeHeadNode returns the AWS Batch state of a job's head node, or
+// nil if it is not (or no longer) in AWS Batch. The state is recorded on
+// job but not stored; the reconciler stores it.
+func (a *API) describeHeadNode(ctx context.Context, job *types.Job) (*batchtypes.JobDetail, error) {
+	batchJobID, err := a.findBatchJobID(ctx, job)
+	if err != nil {
+		return nil, nil
+	}
+	describeOutput, err := a.batchClient.DescribeJobs(ctx, &batch.DescribeJobsInput{
+		Jobs: []string{batchJobID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(describeOutput.Jobs) == 0 {
+		return nil, nil
+	}
+	batchJob := describeOutput.Jobs[0]
+	recordBatchStatus(job, batchJob)
+	return &batchJob, nil
+}
+
+// @Summary Stream job logs
+// @Description Tail the CloudWatch log stream of a job's head node as server-sent events. Each event carries one log line and an ID of the form <timestamp>:<index>; reconnecting with that ID in Last-Event-ID (or the last_event_id query parameter) resumes after it. An "end" event with the final status is sent once the job finishes.
+// @Produce text/event-stream
+// @Param   id path string true "Job ID"
+// @Param   Last-Event-ID header string false "ID of the last event received"
+// @Param   last_event_id query string false "ID of the last event received"
+// @Success 200 {string} string "Server-sent events"
+// @Router /jobs/{id}/logs/stream [get]
+func (a *API) StreamJobLogs(c *gin.Context) {
+	ctx := c.Request.Context()
+	job, err := a.jobStore.GetJob(ctx, c.Param("id"))
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	cursor := logCursor{n: -1}
+	skip := 0
+	if lastEventID != "" {
+		if cursor, err = parseLogCursor(lastEventID); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		// The first read starts at the cursor's timestamp, so the events
+		// up to and including the cursor come around again
+		skip = cursor.n + 1
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+	c.Writer.Flush()
+
+	var (
+		streamName string
+		nextToken  *string
+		status     = job.Status
+		checkedAt  time.Time
+		wroteAt    = time.Now()
+		finishing  bool
+	)
+	ticker := time.NewTicker(logStreamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if time.Since(checkedAt) >= logStreamStatusInterval || finishing {
+			batchJob, err := a.describeHeadNode(ctx, job)
+			if err != nil {
+				log.Printf("Error describing head node of job %s: %v", job.ID, err)
+				writeSSE(c.Writer, "", "error", err.Error())
+				c.Writer.Flush()
+				return
+			}
+			checkedAt = time.Now()
+			status = job.Status
+			if batchJob != nil {
+				status = string(batchJob.Status)
+				state := newBatchState(*batchJob)
+				if state.LogStreamName != "" && state.LogStreamName != streamName {
+					// A retry logs to a new stream; pick up where the last
+					// one stopped
+					streamName = state.LogStreamName
+					nextToken = nil
+				}
+			}
+		}
+
+		if streamName != "" {
+			for {
+				input := &cloudwatchlogs.GetLogEventsInput{
+					LogGroupName:  aws.String(a.config.BatchLogGroup),
+					LogStreamName: aws.String(streamName),
+					StartFromHead: aws.Bool(true),
+					NextToken:     nextToken,
+				}
+				if nextToken == nil && cursor.ts > 0 {
+					input.StartTime = aws.Int64(cursor.ts)
+				}
+				output, err := a.logsClient.GetLogEvents(ctx, input)
+				if err != nil {
+					var notFound *logstypes.ResourceNotFoundException
+					if errors.As(err, &notFound) {
+						// The stream appears with the first line logged
+						break
+					}
+					if ctx.Err() != nil {
+						return
+					}
+					log.Printf("Error getting log events of job %s: %v", job.ID, err)
+					writeSSE(c.Writer, "", "error", err.Error())
+					c.Writer.Flush()
+					return
+				}
+
+				for _, event := range output.Events {
+					if !cursor.advance(aws.ToInt64(event.Timestamp), &skip) {
+						continue
+					}
+					if err := writeSSE(c.Writer, cursor.String(), "", aws.ToString(event.Message)); err != nil {
+						return
+					}
+					wroteAt = time.Now()
+				}
+				c.Writer.Flush()
+
+				// The forward token stays the same at the end of the stream
+				done := nextToken != nil && aws.ToString(output.NextForwardToken) == aws.ToString(nextToken)
+				nextToken = output.NextForwardToken
+				if done || len(output.Events) == 0 {
+					break
+				}
+			}
+			skip = 0
+		}
+
+		if isTerminalStatus(batchtypes.JobStatus(status)) {
+			// Give CloudWatch one more poll to deliver the last lines
+			if finishing {
+				writeSSE(c.Writer, "", "end", status)
+				c.Writer.Flush()
+				return
+			}
+			finishing = true
+		}
+
+		if time.Since(wroteAt) >= logStreamHeartbeat {
+			if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+			wroteAt = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLogCursor(t *testing.T) {
+	tests := []struct {
+		id   string
+		want logCursor
+	}{
+		{"1700000000000:0", logCursor{ts: 1700000000000}},
+		{"1700000000000:3", logCursor{ts: 1700000000000, n: 3}},
+		{"0:0", logCursor{}},
+	}
+	for _, tt := range tests {
+		got, err := parseLogCursor(tt.id)
+		if err != nil || got != tt.want {
+			t.Errorf("parseLogCursor(%q) = %+v, %v, want %+v", tt.id, got, err, tt.want)
+		}
+		if got.String() != tt.id {
+			t.Errorf("expected %+v to print as %q, got %q", got, tt.id, got.String())
+		}
+	}
+	for _, id := range []string{"", "1700000000000", "abc:0", "1700000000000:x", "1700000000000:-1"} {
+		if _, err := parseLogCursor(id); err == nil {
+			t.Errorf("parseLogCursor(%q) expected an error", id)
+		}
+	}
+}
+
+func TestWriteSSE(t *testing.T) {
+	tests := []struct {
+		id, event, data string
+		want            string
+	}{
+		{"100:0", "", "hello", "id: 100:0\ndata: hello\n\n"},
+		{"", "end", "SUCCEEDED", "event: end\ndata: SUCCEEDED\n\n"},
+		{"100:1", "", "line 1\nline 2\n", "id: 100:1\ndata: line 1\ndata: line 2\n\n"},
+		{"", "", "", "data: \n\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := writeSSE(&b, tt.id, tt.event, tt.data); err != nil {
+			t.Fatalf("writeSSE: %v", err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("writeSSE(%q, %q, %q) = %q, want %q", tt.id, tt.event, tt.data, b.String(), tt.want)
+		}
+	}
+}
+
+func TestLogCursorAdvance(t *testing.T) {
+	// Timestamps of a log with three lines in the same millisecond
+	stamps := []int64{100, 100, 100, 200, 300}
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		read        []int64 // What the first read returns
+		want        []string
+	}{
+		{"from the start", "", stamps, []string{"100:0", "100:1", "100:2", "200:0", "300:0"}},
+		{"within a millisecond", "100:1", stamps, []string{"100:2", "200:0", "300:0"}},
+		{"after a millisecond", "100:2", stamps, []string{"200:0", "300:0"}},
+		{"read from the cursor", "200:0", stamps[3:], []string{"300:0"}},
+		{"up to date", "300:0", stamps[4:], nil},
+	}
+	for _, tt := range tests {
+		cursor := logCursor{n: -1}
+		skip := 0
+		if tt.lastEventID != "" {
+			var err error
+			if cursor, err = parseLogCursor(tt.lastEventID); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			skip = cursor.n + 1
+		}
+
+		var got []string
+		for _, ts := range tt.read {
+			if cursor.advance(ts, &skip) {
+				got = append(got, cursor.String())
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: sent %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
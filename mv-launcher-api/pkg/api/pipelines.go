@@ -1,7 +1,15 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/paramschema"
 	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,10 +20,262 @@ import (
 // @Success 200 {object} types.Pipelines
 // @Router /pipeline [get]
 func (a API) ListPipelines(c *gin.Context) {
-	pipelines, err := services.GetPipelines(a.s3Client, a.config.PipelineBucket)
+	pipelines, err := services.GetPipelines(c.Request.Context(), a.s3Client, a.config.PipelineBucket)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(200, pipelines)
 }
+
+// @Summary Get a pipeline
+// @Description Returns a single pipeline from the registry
+// @Accept  json
+// @Produce json
+// @Param   name path string true "Pipeline name"
+// @Success 200 {object} types.Pipeline
+// @Router /pipelines/{name} [get]
+func (a API) GetPipeline(c *gin.Context) {
+	pipeline, err := services.GetPipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, c.Param("name"))
+	if err != nil {
+		respondPipelineError(c, err)
+		return
+	}
+	c.JSON(200, pipeline)
+}
+
+// @Summary Register a pipeline
+// @Description Add a pipeline to the registry. Names are unique.
+// @Accept  json
+// @Produce json
+// @Param   pipeline body types.Pipeline true "Pipeline"
+// @Success 201 {object} types.Pipeline
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Router /pipelines [post]
+func (a API) CreatePipeline(c *gin.Context) {
+	var pipeline types.Pipeline
+	if err := c.ShouldBindJSON(&pipeline); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err := pipeline.Verify(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Entries may predate the <name>.json layout, so check names, not keys
+	pipelines, err := services.GetPipelines(c.Request.Context(), a.s3Client, a.config.PipelineBucket)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	for _, existing := range pipelines {
+		if existing.Name == pipeline.Name {
+			c.JSON(409, gin.H{"error": "A pipeline named " + pipeline.Name + " already exists"})
+			return
+		}
+	}
+
+	pipeline.CreatedAt = time.Now().UTC()
+	pipeline.UpdatedAt = pipeline.CreatedAt
+	if err := services.PutPipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, pipeline); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Registered pipeline %s (%s)", pipeline.Name, pipeline.SourceRepo)
+	c.JSON(201, pipeline)
+}
+
+// @Summary Update a pipeline
+// @Description Replace a pipeline in the registry. The name cannot change.
+// @Accept  json
+// @Produce json
+// @Param   name path string true "Pipeline name"
+// @Param   pipeline body types.Pipeline true "Pipeline"
+// @Success 200 {object} types.Pipeline
+// @Failure 422 {object} map[string]interface{}
+// @Router /pipelines/{name} [put]
+func (a API) UpdatePipeline(c *gin.Context) {
+	name := c.Param("name")
+	existing, err := services.GetPipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, name)
+	if err != nil {
+		respondPipelineError(c, err)
+		return
+	}
+
+	var pipeline types.Pipeline
+	if err := c.ShouldBindJSON(&pipeline); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if pipeline.Name == "" {
+		pipeline.Name = name
+	}
+	if err := pipeline.Verify(); err != nil {
+		respondError(c, err)
+		return
+	}
+	if pipeline.Name != name {
+		respondError(c, types.ValidationErrors{{Field: "name", Message: "cannot be changed"}})
+		return
+	}
+
+	pipeline.CreatedAt = existing.CreatedAt
+	pipeline.UpdatedAt = time.Now().UTC()
+	if err := services.PutPipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, pipeline); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Updated pipeline %s", pipeline.Name)
+	c.JSON(200, pipeline)
+}
+
+// @Summary Delete a pipeline
+// @Description Remove a pipeline from the registry
+// @Accept  json
+// @Produce json
+// @Param   name path string true "Pipeline name"
+// @Success 204
+// @Router /pipelines/{name} [delete]
+func (a API) DeletePipeline(c *gin.Context) {
+	name := c.Param("name")
+	if _, err := services.GetPipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, name); err != nil {
+		respondPipelineError(c, err)
+		return
+	}
+	if err := services.DeletePipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, name); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.DeletePipelineSchema(c.Request.Context(), a.s3Client, a.config.PipelineBucket, name); err != nil {
+		log.Printf("Error deleting schema of pipeline %s: %v", name, err)
+	}
+	log.Printf("Deleted pipeline %s", name)
+	c.Status(204)
+}
+
+// @Summary Get a pipeline's parameter schema
+// @Description Returns the nextflow_schema.json job parameters are validated against
+// @Accept  json
+// @Produce json
+// @Param   name path string true "Pipeline name"
+// @Success 200 {object} map[string]interface{}
+// @Router /pipelines/{name}/schema [get]
+func (a API) GetPipelineSchema(c *gin.Context) {
+	schema, err := services.GetPipelineSchema(c.Request.Context(), a.s3Client, a.config.PipelineBucket, c.Param("name"))
+	if err != nil {
+		respondPipelineError(c, err)
+		return
+	}
+	c.Data(200, "application/json", schema)
+}
+
+// @Summary Store a pipeline's parameter schema
+// @Description Upload the nextflow_schema.json job parameters are validated against
+// @Accept  json
+// @Produce json
+// @Param   name path string true "Pipeline name"
+// @Param   schema body map[string]interface{} true "nextflow_schema.json"
+// @Success 204
+// @Failure 422 {object} map[string]interface{}
+// @Router /pipelines/{name}/schema [put]
+func (a API) PutPipelineSchema(c *gin.Context) {
+	name := c.Param("name")
+	if _, err := services.GetPipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, name); err != nil {
+		respondPipelineError(c, err)
+		return
+	}
+
+	schema, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	a.storePipelineSchema(c, name, schema)
+}
+
+// @Summary Import a pipeline's parameter schema
+// @Description Fetch nextflow_schema.json from the pipeline's GitHub repository at the given or default revision
+// @Accept  json
+// @Produce json
+// @Param   name path string true "Pipeline name"
+// @Param   revision query string false "Revision to import the schema from"
+// @Success 204
+// @Failure 422 {object} map[string]interface{}
+// @Router /pipelines/{name}/schema/import [post]
+func (a API) ImportPipelineSchema(c *gin.Context) {
+	name := c.Param("name")
+	pipeline, err := services.GetPipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, name)
+	if err != nil {
+		respondPipelineError(c, err)
+		return
+	}
+
+	revision := c.DefaultQuery("revision", pipeline.DefaultRevision)
+	schema, err := services.FetchPipelineSchema(c.Request.Context(), pipeline.SourceRepo, revision)
+	if err != nil {
+		log.Printf("Error importing schema of pipeline %s: %v", name, err)
+		respondError(c, types.ValidationErrors{{Field: "source_repo", Message: err.Error()}})
+		return
+	}
+	a.storePipelineSchema(c, name, schema)
+}
+
+// storePipelineSchema checks a schema parses before storing it, so job
+// validation never trips over a broken one
+func (a API) storePipelineSchema(c *gin.Context, name string, schema []byte) {
+	if _, err := paramschema.Parse(schema); err != nil {
+		respondError(c, types.ValidationErrors{{Field: "schema", Message: err.Error()}})
+		return
+	}
+	if err := services.PutPipelineSchema(c.Request.Context(), a.s3Client, a.config.PipelineBucket, name, schema); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Stored parameter schema of pipeline %s", name)
+	c.Status(204)
+}
+
+// lookupPipeline finds the registry entry and parameter schema of a job's
+// pipeline. Either is nil if the pipeline is not registered or has no
+// schema.
+func (a *API) lookupPipeline(ctx context.Context, ref string) (*types.Pipeline, *paramschema.Schema, error) {
+	if a.config.PipelineBucket == "" || ref == "" {
+		return nil, nil, nil
+	}
+	pipeline, err := services.FindPipeline(ctx, a.s3Client, a.config.PipelineBucket, ref)
+	if errors.Is(err, services.ErrPipelineNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := services.GetPipelineSchema(ctx, a.s3Client, a.config.PipelineBucket, pipeline.Name)
+	if errors.Is(err, services.ErrSchemaNotFound) {
+		return pipeline, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, err := paramschema.Parse(data)
+	if err != nil {
+		log.Printf("Ignoring invalid schema of pipeline %s: %v", pipeline.Name, err)
+		return pipeline, nil, nil
+	}
+	return pipeline, schema, nil
+}
+
+// respondPipelineError maps registry lookup errors to responses
+func respondPipelineError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPipelineNotFound) {
+		c.JSON(404, gin.H{"error": "Pipeline not found"})
+		return
+	}
+	if errors.Is(err, services.ErrSchemaNotFound) {
+		c.JSON(404, gin.H{"error": "Pipeline has no parameter schema"})
+		return
+	}
+	c.JSON(500, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/service/batch"
+	batchtypes "github.com/aws/aws-sdk-go-v2/service/batch/types"
+)
+
+// RunReconciler periodically syncs the AWS Batch state of unfinished jobs
+// into the job store until ctx is cancelled. A zero interval disables it.
+func (a *API) RunReconciler(ctx context.Context) {
+	interval := a.config.ReconcileInterval
+	if interval <= 0 {
+		log.Printf("Job reconciler disabled")
+		return
+	}
+
+	log.Printf("Starting job reconciler with interval %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.reconcileJobs(ctx); err != nil {
+			log.Printf("Error reconciling jobs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Job reconciler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileJobs describes every unfinished job in AWS Batch and stores the
+// ones whose state changed
+func (a *API) reconcileJobs(ctx context.Context) error {
+	jobs, err := a.jobStore.GetJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[string]*types.Job)
+	jobIds := make([]string, 0)
+	for i := range jobs {
+		job := &jobs[i]
+		if job.IsTerminal() || job.BatchJobId == "" {
+			continue
+		}
+		pending[job.BatchJobId] = job
+		jobIds = append(jobIds, job.BatchJobId)
+	}
+	// Release queued jobs once the statuses below are up to date
+	defer a.releaseQueuedJobs(ctx, jobs)
+	if len(jobIds) == 0 {
+		return nil
+	}
+
+	// Describe jobs in batches of 100 (AWS Batch limit)
+	updated := 0
+	for i := 0; i < len(jobIds); i += 100 {
+		end := i + 100
+		if end > len(jobIds) {
+			end = len(jobIds)
+		}
+
+		describeOutput, err := a.batchClient.DescribeJobs(ctx, &batch.DescribeJobsInput{
+			Jobs: jobIds[i:end],
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Error describing jobs: %v", err)
+			continue
+		}
+
+		for _, batchJob := range describeOutput.Jobs {
+			job, ok := pending[*batchJob.JobId]
+			if !ok {
+				continue
+			}
+			if a.syncJobStatus(ctx, job, batchJob) {
+				updated++
+			}
+		}
+	}
+
+	log.Printf("Reconciled %d unfinished jobs, %d updated", len(jobIds), updated)
+	return nil
+}
+
+// releaseQueuedJobs submits queued jobs whose dependencies have succeeded,
+// and fails the ones whose dependencies failed. Job group children are
+// released oldest first while their group has fewer than its concurrency
+// running.
+func (a *API) releaseQueuedJobs(ctx context.Context, jobs types.Jobs) {
+	byID := make(map[string]*types.Job, len(jobs))
+	for i := range jobs {
+		byID[jobs[i].ID] = &jobs[i]
+	}
+
+	active := make(map[string]int)
+	queued := make(map[string][]*types.Job)
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Status != types.JobStatusQueued {
+			if job.GroupID != "" && !job.IsTerminal() {
+				active[job.GroupID]++
+			}
+			continue
+		}
+
+		state, reason := checkDependencies(job, byID)
+		switch state {
+		case dependenciesPending:
+			continue
+		case dependenciesFailed:
+			err := a.withQueuedJob(ctx, job.ID, func(queued *types.Job) error {
+				queued.RecordStatus(string(batchtypes.JobStatusFailed), reason, time.Now().UTC())
+				*job = *queued
+				return a.jobStore.PutJob(ctx, *queued)
+			})
+			switch {
+			case errors.Is(err, errJobDequeued):
+				// Cancelled since the jobs were read
+			case err != nil:
+				log.Printf("Error storing job %s: %v", job.ID, err)
+			default:
+				log.Printf("Job %s will not run: %s", job.ID, reason)
+			}
+			continue
+		}
+
+		if job.GroupID == "" {
+			if err := a.releaseQueuedJob(ctx, job); err != nil && !errors.Is(err, errJobDequeued) {
+				log.Printf("Error submitting queued job %s: %v", job.ID, err)
+			}
+			continue
+		}
+		queued[job.GroupID] = append(queued[job.GroupID], job)
+	}
+
+	for groupID, waiting := range queued {
+		if ctx.Err() != nil {
+			return
+		}
+		group, err := services.GetJobGroup(ctx, a.s3Client, a.config.JobBucket, groupID)
+		if err != nil {
+			log.Printf("Error getting job group %s: %v", groupID, err)
+			continue
+		}
+
+		free := group.Concurrency - active[groupID]
+		if free <= 0 {
+			continue
+		}
+		sort.Slice(waiting, func(i, j int) bool {
+			return waiting[i].CreatedAt.Before(waiting[j].CreatedAt)
+		})
+		released := 0
+		for _, job := range waiting[:min(free, len(waiting))] {
+			if err := a.releaseQueuedJob(ctx, job); err != nil {
+				if !errors.Is(err, errJobDequeued) {
+					log.Printf("Error submitting queued job %s of group %s: %v", job.ID, groupID, err)
+				}
+				continue
+			}
+			released++
+		}
+		log.Printf("Released %d of %d queued jobs of group %s", released, len(waiting), groupID)
+	}
+}
+
+// releaseQueuedJob submits a queued job, unless it was cancelled since the
+// reconciler read it, in which case errJobDequeued is returned. A job that
+// keeps failing to submit is failed after types.MaxSubmitAttempts.
+func (a *API) releaseQueuedJob(ctx context.Context, job *types.Job) error {
+	return a.withQueuedJob(ctx, job.ID, func(queued *types.Job) error {
+		defer func() { *job = *queued }()
+		_, err := a.submitJob(ctx, queued)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		if queued.RecordSubmitFailure(err, time.Now().UTC()) {
+			log.Printf("Job %s failed after %d submission attempts", queued.ID, queued.SubmitAttempts)
+		}
+		if err := a.jobStore.PutJob(ctx, *queued); err != nil {
+			log.Printf("Error storing job %s: %v", queued.ID, err)
+		}
+		return err
+	})
+}
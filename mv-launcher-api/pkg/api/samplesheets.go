@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/samplesheet"
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxSamplesheetSize bounds samplesheet uploads
+const maxSamplesheetSize = 10 << 20
+
+// @Summary Upload a samplesheet
+// @Description Upload a CSV or TSV samplesheet. It is checked against the column spec of the given pipeline and every S3 file it references must exist. Jobs use it as their --input by setting samplesheet_id.
+// @Accept  multipart/form-data
+// @Produce json
+// @Param   file formData file true "Samplesheet (.csv or .tsv)"
+// @Param   pipeline formData string false "Registered pipeline whose column spec to check against"
+// @Success 201 {object} types.Samplesheet
+// @Failure 422 {object} map[string]interface{}
+// @Router /samplesheets [post]
+func (a *API) CreateSamplesheet(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSamplesheetSize)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "A samplesheet file is required: " + err.Error()})
+		return
+	}
+	defer file.Close()
+
+	body, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	format, err := samplesheet.Format(header.Filename)
+	if err != nil {
+		respondError(c, types.ValidationErrors{{Field: "file", Message: err.Error()}})
+		return
+	}
+
+	var spec *types.SamplesheetSpec
+	pipelineName := c.PostForm("pipeline")
+	if pipelineName != "" {
+		pipeline, err := services.FindPipeline(c.Request.Context(), a.s3Client, a.config.PipelineBucket, pipelineName)
+		if errors.Is(err, services.ErrPipelineNotFound) {
+			respondError(c, types.ValidationErrors{{Field: "pipeline", Message: "is not registered"}})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		pipelineName = pipeline.Name
+		spec = pipeline.Samplesheet
+	}
+
+	sheet, err := samplesheet.Parse(bytes.NewReader(body), format)
+	if err != nil {
+		respondError(c, types.ValidationErrors{{Field: "file", Message: err.Error()}})
+		return
+	}
+	files, errs := sheet.Validate(spec)
+	missing := services.MissingObjects(c.Request.Context(), a.s3Client, files)
+	missingURIs := make([]string, 0, len(missing))
+	for uri, err := range missing {
+		log.Printf("Samplesheet file %s is not readable: %v", uri, err)
+		missingURIs = append(missingURIs, uri)
+	}
+	sort.Strings(missingURIs)
+	for _, uri := range missingURIs {
+		errs.Add("files", "%s does not exist or is not readable", uri)
+	}
+	if err := errs.Err(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	id := uuid.New().String()
+	meta := types.Samplesheet{
+		ID:        id,
+		Pipeline:  pipelineName,
+		FileName:  header.Filename,
+		Format:    format,
+		Columns:   sheet.Header,
+		Rows:      len(sheet.Rows),
+		URI:       services.SamplesheetURI(a.config.JobBucket, id, format),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := services.PutSamplesheet(c.Request.Context(), a.s3Client, a.config.JobBucket, meta, body); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Stored samplesheet %s with %d rows at %s", meta.ID, meta.Rows, meta.URI)
+	c.JSON(201, meta)
+}
+
+// @Summary Get a samplesheet
+// @Description Returns the metadata of an uploaded samplesheet
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Samplesheet ID"
+// @Success 200 {object} types.Samplesheet
+// @Router /samplesheets/{id} [get]
+func (a *API) GetSamplesheet(c *gin.Context) {
+	sheet, err := services.GetSamplesheet(c.Request.Context(), a.s3Client, a.config.JobBucket, c.Param("id"))
+	if errors.Is(err, services.ErrSamplesheetNotFound) {
+		c.JSON(404, gin.H{"error": "Samplesheet not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, sheet)
+}
+
+// resolveSamplesheet points the input parameter of a job at the samplesheet
+// it references
+func (a *API) resolveSamplesheet(ctx context.Context, job *types.Job, errs *types.ValidationErrors) error {
+	if job.SamplesheetID == "" {
+		return nil
+	}
+	sheet, err := services.GetSamplesheet(ctx, a.s3Client, a.config.JobBucket, job.SamplesheetID)
+	if errors.Is(err, services.ErrSamplesheetNotFound) {
+		errs.Add("samplesheet_id", "samplesheet %q does not exist", job.SamplesheetID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Relaunched jobs carry the input resolved for their parent
+	if input, ok := job.Parameters["input"]; ok && input != sheet.URI {
+		errs.Add("parameters.input", "cannot be set together with samplesheet_id")
+		return nil
+	}
+	if job.Parameters == nil {
+		job.Parameters = make(map[string]interface{})
+	}
+	job.Parameters["input"] = sheet.URI
+	return nil
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+// RunScheduler periodically launches the runs of schedules that are due
+// until ctx is cancelled. A zero interval disables it. Only one launcher
+// instance should run the scheduler against a job bucket.
+func (a *API) RunScheduler(ctx context.Context) {
+	interval := a.config.ScheduleInterval
+	if interval <= 0 {
+		log.Printf("Scheduler disabled")
+		return
+	}
+
+	log.Printf("Starting scheduler with interval %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.runSchedules(ctx, time.Now().UTC()); err != nil {
+			log.Printf("Error running schedules: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runSchedules ticks every active schedule
+func (a *API) runSchedules(ctx context.Context, now time.Time) error {
+	schedules, err := services.GetSchedules(ctx, a.s3Client, a.config.JobBucket)
+	if err != nil {
+		return err
+	}
+	for i := range schedules {
+		if schedules[i].Paused {
+			continue
+		}
+		err := a.tickSchedule(ctx, &schedules[i], now)
+		// A schedule deleted meanwhile has nothing left to record
+		if err != nil && !errors.Is(err, services.ErrScheduleNotFound) {
+			log.Printf("Error running schedule %s: %v", schedules[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// tickSchedule launches the runs of a schedule that are due and records
+// them. schedulesMu is held while the schedule is read, changed and stored,
+// but not while its runs launch, so the API is not blocked meanwhile. A
+// schedule changed since it was listed is ticked next time.
+func (a *API) tickSchedule(ctx context.Context, listed *types.Schedule, now time.Time) error {
+	active, err := a.scheduleActive(ctx, listed)
+	if err != nil {
+		return fmt.Errorf("failed to check the last run: %v", err)
+	}
+
+	var launch []time.Time
+	schedule, err := a.updateSchedule(ctx, listed.ID, func(schedule *types.Schedule) bool {
+		// active was worked out from the listed schedule; if the schedule
+		// was updated since, leave it to the next tick
+		if schedule.Paused || !schedule.UpdatedAt.Equal(listed.UpdatedAt) {
+			return false
+		}
+		var changed bool
+		var err error
+		launch, changed, err = schedule.Tick(now, active)
+		if err != nil {
+			log.Printf("Error computing the next run of schedule %s: %v", schedule.ID, err)
+		}
+		return changed
+	})
+	if err != nil || len(launch) == 0 {
+		return err
+	}
+
+	runs := make([]types.ScheduleRun, 0, len(launch))
+	for _, scheduledAt := range launch {
+		runs = append(runs, a.launchScheduledRun(ctx, *schedule, scheduledAt, now))
+	}
+	_, err = a.updateSchedule(ctx, schedule.ID, func(schedule *types.Schedule) bool {
+		for _, run := range runs {
+			schedule.RecordRun(run)
+		}
+		return true
+	})
+	return err
+}
+
+// updateSchedule applies fn to a stored schedule under schedulesMu and
+// stores it if fn reports a change
+func (a *API) updateSchedule(ctx context.Context, id string, fn func(schedule *types.Schedule) bool) (*types.Schedule, error) {
+	a.schedulesMu.Lock()
+	defer a.schedulesMu.Unlock()
+
+	schedule, err := services.GetSchedule(ctx, a.s3Client, a.config.JobBucket, id)
+	if err != nil {
+		return nil, err
+	}
+	if !fn(schedule) {
+		return schedule, nil
+	}
+	schedule.UpdatedAt = time.Now().UTC()
+	if err := services.PutSchedule(ctx, a.s3Client, a.config.JobBucket, *schedule); err != nil {
+		return nil, fmt.Errorf("failed to store schedule: %v", err)
+	}
+	return schedule, nil
+}
+
+// scheduleActive reports whether the job a schedule launched last is still
+// running
+func (a *API) scheduleActive(ctx context.Context, schedule *types.Schedule) (bool, error) {
+	for i := len(schedule.Runs) - 1; i >= 0; i-- {
+		run := schedule.Runs[i]
+		if run.JobID == "" {
+			continue
+		}
+		job, err := a.jobStore.GetJob(ctx, run.JobID)
+		if errors.Is(err, services.ErrJobNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return !job.IsTerminal(), nil
+	}
+	return false, nil
+}
+
+// launchScheduledRun launches a job from the template of a schedule the way
+// CreateJob does and returns the run to record
+func (a *API) launchScheduledRun(ctx context.Context, schedule types.Schedule, scheduledAt time.Time, now time.Time) types.ScheduleRun {
+	job := schedule.Template
+	job.Parameters = jobParams(&schedule.Template)
+	job.ScheduleID = schedule.ID
+	if loc, err := time.LoadLocation(schedule.Timezone); err == nil {
+		scheduledAt = scheduledAt.In(loc)
+	}
+	job.Name = fmt.Sprintf("%s-%s", schedule.Name, scheduledAt.Format("20060102-1504"))
+
+	run := types.ScheduleRun{
+		ScheduledAt: scheduledAt.UTC(),
+		At:          now,
+	}
+	err := a.validateJob(ctx, &job)
+	if err == nil {
+		err = a.launchJob(ctx, &job)
+	}
+	if err != nil {
+		log.Printf("Error launching run of schedule %s: %v", schedule.ID, err)
+		run.Outcome = types.ScheduleRunFailed
+		run.Message = err.Error()
+		return run
+	}
+
+	log.Printf("Schedule %s launched job %s", schedule.ID, job.ID)
+	run.JobID = job.ID
+	run.Outcome = types.ScheduleRunLaunched
+	return run
+}
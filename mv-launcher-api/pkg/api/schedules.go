@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// @Summary List schedules
+// @Description Returns every schedule with its recent runs
+// @Accept  json
+// @Produce json
+// @Success 200 {array} types.Schedule
+// @Router /schedules [get]
+func (a *API) ListSchedules(c *gin.Context) {
+	schedules, err := services.GetSchedules(c.Request.Context(), a.s3Client, a.config.JobBucket)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range schedules {
+		schedules[i] = schedules[i].Redacted()
+	}
+	c.JSON(200, schedules)
+}
+
+// @Summary Get a schedule
+// @Description Returns a schedule with its recent runs
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Schedule ID"
+// @Success 200 {object} types.Schedule
+// @Router /schedules/{id} [get]
+func (a *API) GetSchedule(c *gin.Context) {
+	schedule, err := services.GetSchedule(c.Request.Context(), a.s3Client, a.config.JobBucket, c.Param("id"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(200, schedule.Redacted())
+}
+
+// @Summary Create a schedule
+// @Description Launch a job from a template on a cron schedule. The overlap policy (skip, queue or allow) decides what happens when the schedule fires while its last job still runs.
+// @Accept  json
+// @Produce json
+// @Param   schedule body types.Schedule true "Schedule"
+// @Success 201 {object} types.Schedule
+// @Failure 422 {object} map[string]interface{}
+// @Router /schedules [post]
+func (a *API) CreateSchedule(c *gin.Context) {
+	var schedule types.Schedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err := a.validateSchedule(c.Request.Context(), &schedule); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	schedule.ID = uuid.New().String()
+	schedule.Runs = nil
+	schedule.QueuedRuns = nil
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	next, err := schedule.Next(now)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	schedule.NextRunAt = next
+
+	if err := services.PutSchedule(c.Request.Context(), a.s3Client, a.config.JobBucket, schedule); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Created schedule %s (%s %s), next run at %s", schedule.ID, schedule.Cron, schedule.Timezone, schedule.NextRunAt)
+	c.JSON(201, schedule.Redacted())
+}
+
+// @Summary Update a schedule
+// @Description Replace the cron expression, time zone, overlap policy, pause state or template of a schedule. Its run history is kept.
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Schedule ID"
+// @Param   schedule body types.Schedule true "Schedule"
+// @Success 200 {object} types.Schedule
+// @Failure 422 {object} map[string]interface{}
+// @Router /schedules/{id} [put]
+func (a *API) UpdateSchedule(c *gin.Context) {
+	var schedule types.Schedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err := a.validateSchedule(c.Request.Context(), &schedule); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	a.schedulesMu.Lock()
+	defer a.schedulesMu.Unlock()
+
+	ctx := c.Request.Context()
+	existing, err := services.GetSchedule(ctx, a.s3Client, a.config.JobBucket, c.Param("id"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	schedule.ID = existing.ID
+	schedule.Runs = existing.Runs
+	schedule.QueuedRuns = nil
+	if schedule.Overlap == types.OverlapQueue {
+		schedule.QueuedRuns = existing.QueuedRuns
+	}
+	schedule.CreatedAt = existing.CreatedAt
+	schedule.UpdatedAt = now
+	next, err := schedule.Next(now)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	schedule.NextRunAt = next
+
+	if err := services.PutSchedule(ctx, a.s3Client, a.config.JobBucket, schedule); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Updated schedule %s, next run at %s", schedule.ID, schedule.NextRunAt)
+	c.JSON(200, schedule.Redacted())
+}
+
+// @Summary Delete a schedule
+// @Description Stop and remove a schedule. Jobs it launched are not affected.
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Schedule ID"
+// @Success 204
+// @Router /schedules/{id} [delete]
+func (a *API) DeleteSchedule(c *gin.Context) {
+	a.schedulesMu.Lock()
+	defer a.schedulesMu.Unlock()
+
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	if _, err := services.GetSchedule(ctx, a.s3Client, a.config.JobBucket, id); err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	if err := services.DeleteSchedule(ctx, a.s3Client, a.config.JobBucket, id); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Deleted schedule %s", id)
+	c.Status(204)
+}
+
+// validateSchedule checks a schedule and the job template it launches, so
+// mistakes surface now rather than at its first run
+func (a *API) validateSchedule(ctx context.Context, schedule *types.Schedule) error {
+	var errs types.ValidationErrors
+	if err := schedule.Verify(); err != nil && !errors.As(err, &errs) {
+		return err
+	}
+
+	template := schedule.Template
+	template.Parameters = jobParams(&schedule.Template)
+	if err := a.validateJob(ctx, &template); err != nil {
+		var templateErrs types.ValidationErrors
+		if !errors.As(prefixFieldErrors(err, "template."), &templateErrs) {
+			return err
+		}
+		for _, fieldErr := range templateErrs {
+			// Verify already reported a missing pipeline
+			if fieldErr.Field != "template.pipeline" || schedule.Template.Pipeline != "" {
+				errs = append(errs, fieldErr)
+			}
+		}
+	}
+	return errs.Err()
+}
+
+// respondScheduleError maps schedule lookup errors to responses
+func respondScheduleError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrScheduleNotFound) {
+		c.JSON(404, gin.H{"error": "Schedule not found"})
+		return
+	}
+	c.JSON(500, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+const maxTaskListLimit = 10000
+
+// TaskList is the tasks of a job's run, merged from its weblog events and
+// its trace
+type TaskList struct {
+	Tasks   []types.Task `json:"tasks"`
+	Total   int          `json:"total"`
+	Sources []string     `json:"sources" example:"weblog,trace"`
+}
+
+// taskSorters orders tasks by the fields accepted in the sort parameter
+var taskSorters = map[string]func(a, b types.Task) bool{
+	"task_id":      func(a, b types.Task) bool { return a.TaskID < b.TaskID },
+	"submitted_at": func(a, b types.Task) bool { return a.SubmittedAt.Before(b.SubmittedAt) },
+	"duration":     func(a, b types.Task) bool { return a.Duration < b.Duration },
+	"realtime":     func(a, b types.Task) bool { return a.Realtime < b.Realtime },
+	"cpu_percent":  func(a, b types.Task) bool { return a.CPUPercent < b.CPUPercent },
+	"peak_rss":     func(a, b types.Task) bool { return a.PeakRSS < b.PeakRSS },
+}
+
+// @Summary List job tasks
+// @Description Returns the Nextflow tasks of a job's run. Live status comes from weblog events, resource usage from the trace the head node uploads as the run progresses.
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Param   process query string false "Comma-separated process names, fully qualified or not"
+// @Param   status query string false "Comma-separated task statuses, e.g. FAILED"
+// @Param   sort query string false "Sort field, prefixed with - for descending (default task_id)"
+// @Param   limit query int false "Maximum number of tasks (max 10000)"
+// @Success 200 {object} TaskList
+// @Router /jobs/{id}/tasks [get]
+func (a *API) ListJobTasks(c *gin.Context) {
+	sortField := c.DefaultQuery("sort", "task_id")
+	descending := strings.HasPrefix(sortField, "-")
+	less, ok := taskSorters[strings.TrimPrefix(sortField, "-")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot sort by %s", sortField)})
+		return
+	}
+
+	limit := maxTaskListLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxTaskListLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxTaskListLimit)})
+			return
+		}
+		limit = n
+	}
+
+	ctx := c.Request.Context()
+	job, err := a.jobStore.GetJob(ctx, c.Param("id"))
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return
+	}
+
+	sources := make([]string, 0, 2)
+	var live []types.Task
+	progress, err := a.getProgress(ctx, job.ID)
+	switch {
+	case err == nil:
+		live = progress.Tasks
+		sources = append(sources, "weblog")
+	case !errors.Is(err, services.ErrProgressNotFound):
+		log.Printf("Error getting progress of job %s: %v", job.ID, err)
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	traced, err := services.GetJobTrace(ctx, a.s3Client, a.logBucket(job), job.ID)
+	switch {
+	case err == nil:
+		sources = append(sources, "trace")
+	case !errors.Is(err, services.ErrTraceNotFound):
+		// A broken trace should not hide what the weblog reported
+		log.Printf("Error reading trace of job %s: %v", job.ID, err)
+	}
+	if len(sources) == 0 {
+		c.JSON(404, gin.H{"error": "Job has not reported any tasks"})
+		return
+	}
+
+	processes := splitFilter(c.Query("process"))
+	statuses := splitFilter(c.Query("status"))
+	tasks := make([]types.Task, 0)
+	for _, task := range types.MergeTasks(live, traced) {
+		if len(processes) > 0 && !processes[strings.ToLower(task.Process)] && !processes[strings.ToLower(simpleProcessName(task.Process))] {
+			continue
+		}
+		if len(statuses) > 0 && !statuses[strings.ToLower(task.Status)] {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if descending {
+			return less(tasks[j], tasks[i])
+		}
+		return less(tasks[i], tasks[j])
+	})
+	total := len(tasks)
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+
+	c.JSON(200, TaskList{Tasks: tasks, Total: total, Sources: sources})
+}
+
+// simpleProcessName strips the workflow scopes from a process name, e.g.
+// NFCORE_RNASEQ:RNASEQ:FASTQC becomes FASTQC
+func simpleProcessName(process string) string {
+	return process[strings.LastIndex(process, ":")+1:]
+}
+
+// splitFilter turns a comma-separated query value into a case-insensitive
+// set
+func splitFilter(value string) map[string]bool {
+	set := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[strings.ToLower(item)] = true
+		}
+	}
+	return set
+}
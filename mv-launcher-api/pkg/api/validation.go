@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/batch"
+	batchtypes "github.com/aws/aws-sdk-go-v2/service/batch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gin-gonic/gin"
+)
+
+// validateJob normalises a job spec and checks it, including that its queues
+// exist and its buckets are reachable. Problems with the spec are returned
+// as types.ValidationErrors.
+func (a *API) validateJob(ctx context.Context, job *types.Job) error {
+	var errs types.ValidationErrors
+	if err := job.Verify(); err != nil {
+		if !errors.As(err, &errs) {
+			return err
+		}
+	}
+	// The head node and the API must agree on where the logs go
+	if job.LogBucket == "" {
+		job.LogBucket = a.config.LogBucket
+	}
+
+	if err := a.resolveDependencies(ctx, job, &errs); err != nil {
+		return err
+	}
+	if err := a.resolveSamplesheet(ctx, job, &errs); err != nil {
+		return err
+	}
+	if err := a.applyPipeline(ctx, job, &errs); err != nil {
+		return err
+	}
+
+	queueFields := map[string]string{
+		"head_node_queue": job.HeadNodeQueue,
+		"task_queue":      job.TaskQueue,
+	}
+	queueNames := make([]string, 0, len(queueFields))
+	for _, queue := range queueFields {
+		if queue != "" {
+			queueNames = append(queueNames, queue)
+		}
+	}
+	if len(queueNames) > 0 {
+		out, err := a.batchClient.DescribeJobQueues(ctx, &batch.DescribeJobQueuesInput{
+			JobQueues: queueNames,
+		})
+		if err != nil {
+			return err
+		}
+		queues := make(map[string]batchtypes.JobQueueDetail)
+		for _, q := range out.JobQueues {
+			queues[aws.ToString(q.JobQueueName)] = q
+			queues[aws.ToString(q.JobQueueArn)] = q
+		}
+		for field, name := range queueFields {
+			if name == "" {
+				continue
+			}
+			q, ok := queues[name]
+			if !ok {
+				errs.Add(field, "queue %q does not exist", name)
+				continue
+			}
+			if q.State != batchtypes.JQStateEnabled || q.Status != batchtypes.JQStatusValid {
+				errs.Add(field, "queue %q is %s/%s, not ENABLED/VALID", name, q.State, q.Status)
+			}
+		}
+	}
+
+	bucketFields := []struct {
+		field  string
+		bucket string
+	}{
+		{"work_dir", types.S3Bucket(job.WorkDir)},
+		{"result_dir", types.S3Bucket(job.ResultDir)},
+		{"log_bucket", job.LogBucket},
+	}
+	reachable := make(map[string]error)
+	for _, bf := range bucketFields {
+		if bf.bucket == "" {
+			continue
+		}
+		err, checked := reachable[bf.bucket]
+		if !checked {
+			_, err = a.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
+				Bucket: aws.String(bf.bucket),
+			})
+			reachable[bf.bucket] = err
+		}
+		if err != nil {
+			log.Printf("Bucket %s is not reachable: %v", bf.bucket, err)
+			errs.Add(bf.field, "bucket %q is not reachable", bf.bucket)
+		}
+	}
+
+	return errs.Err()
+}
+
+// applyPipeline fills in the defaults of the job's registered pipeline and
+// checks the job runs one of its allowed revisions with parameters that
+// match its schema. Parameters are converted to the types the schema
+// declares, so launching needs no second lookup. Pipelines that are not in
+// the registry run as given.
+func (a *API) applyPipeline(ctx context.Context, job *types.Job, errs *types.ValidationErrors) error {
+	// Only the registry decides which repo a pipeline name runs
+	job.SourceRepo = ""
+	pipeline, schema, err := a.lookupPipeline(ctx, job.Pipeline)
+	if err != nil || pipeline == nil {
+		return err
+	}
+	pipeline.ApplyTo(job, errs)
+
+	if schema != nil {
+		params := jobParams(job)
+		// The launcher always passes --outdir itself
+		_, hasOutdir := params["outdir"]
+		if !hasOutdir {
+			params["outdir"] = job.ResultDir
+		}
+		converted, paramErrs := schema.Validate(params)
+		*errs = append(*errs, paramErrs...)
+		if !hasOutdir {
+			delete(converted, "outdir")
+		}
+		if len(job.Parameters) > 0 {
+			job.Parameters = converted
+		}
+	}
+	return nil
+}
+
+// prefixFieldErrors nests the field errors in err under prefix, e.g. the
+// errors of a job template under "template."
+func prefixFieldErrors(err error, prefix string) error {
+	var errs types.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	prefixed := make(types.ValidationErrors, 0, len(errs))
+	for _, fieldErr := range errs {
+		prefixed.Add(prefix+fieldErr.Field, "%s", fieldErr.Message)
+	}
+	return prefixed
+}
+
+// respondError writes a 422 with per-field errors for validation failures
+// and a 500 for anything else
+func respondError(c *gin.Context, err error) {
+	var errs types.ValidationErrors
+	if errors.As(err, &errs) {
+		c.JSON(422, gin.H{
+			"error":  "Validation failed",
+			"fields": errs,
+		})
+		return
+	}
+	c.JSON(500, gin.H{"error": err.Error()})
+}
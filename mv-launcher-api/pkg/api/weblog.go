@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/services"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// progressFlushInterval is how long weblog events may only be held in
+// memory before the progress of a job is written to S3
+const progressFlushInterval = 5 * time.Second
+
+// progressCache holds the progress of running jobs between writes to S3,
+// so that not every weblog event costs a round trip
+type progressCache struct {
+	mu      sync.Mutex
+	entries map[string]*progressEntry
+}
+
+type progressEntry struct {
+	mu        sync.Mutex
+	progress  *types.WorkflowProgress
+	flushedAt time.Time
+}
+
+func newProgressCache() *progressCache {
+	return &progressCache{entries: make(map[string]*progressEntry)}
+}
+
+// entry returns the cache entry of a job, creating an empty one if needed
+func (c *progressCache) entry(jobID string) *progressEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[jobID]
+	if !ok {
+		entry = &progressEntry{}
+		c.entries[jobID] = entry
+	}
+	return entry
+}
+
+// lookup returns the cache entry of a job, or nil if it has none
+func (c *progressCache) lookup(jobID string) *progressEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[jobID]
+}
+
+func (c *progressCache) evict(jobID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, jobID)
+}
+
+// @Summary Ingest a weblog event
+// @Description Receives the events Nextflow posts with -with-weblog. Head nodes are pointed here when LAUNCHER_URL is configured.
+// @Accept  json
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Param   event body types.WeblogEvent true "Weblog event"
+// @Success 200 {object} map[string]string
+// @Router /jobs/{id}/weblog [post]
+func (a *API) IngestWeblog(c *gin.Context) {
+	ctx := c.Request.Context()
+	jobID := c.Param("id")
+
+	var event types.WeblogEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry := a.progress.entry(jobID)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.progress == nil {
+		// The job is only looked up when its events start to be cached, not
+		// for every task event
+		if _, err := a.jobStore.GetJob(ctx, jobID); err != nil {
+			log.Printf("Error getting job spec: %v", err)
+			a.progress.evict(jobID)
+			c.JSON(404, gin.H{"error": "Job not found"})
+			return
+		}
+		progress, err := services.GetWorkflowProgress(ctx, a.s3Client, a.config.JobBucket, jobID)
+		if errors.Is(err, services.ErrProgressNotFound) {
+			progress = &types.WorkflowProgress{JobID: jobID}
+		} else if err != nil {
+			log.Printf("Error getting progress of job %s: %v", jobID, err)
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		entry.progress = progress
+	}
+	entry.progress.Apply(event)
+
+	// The start and end of a run are written straight away, task events at
+	// most every progressFlushInterval
+	lifecycle := event.Event == types.WeblogStarted || event.Event == types.WeblogCompleted
+	if lifecycle || time.Since(entry.flushedAt) >= progressFlushInterval {
+		if err := services.PutWorkflowProgress(ctx, a.s3Client, a.config.JobBucket, *entry.progress); err != nil {
+			log.Printf("Error storing progress of job %s: %v", jobID, err)
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		entry.flushedAt = time.Now()
+	}
+	if event.Event == types.WeblogCompleted {
+		a.progress.evict(jobID)
+	}
+
+	c.JSON(200, gin.H{"status": "ok"})
+}
+
+// flushProgress writes weblog events of a job still held in memory to S3
+// and drops them from the cache, for runs that end without a completed
+// event
+func (a *API) flushProgress(ctx context.Context, jobID string) {
+	entry := a.progress.lookup(jobID)
+	if entry == nil {
+		return
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.progress != nil {
+		if err := services.PutWorkflowProgress(ctx, a.s3Client, a.config.JobBucket, *entry.progress); err != nil {
+			log.Printf("Error storing progress of job %s: %v", jobID, err)
+			return
+		}
+	}
+	a.progress.evict(jobID)
+}
+
+// FlushProgress writes the weblog events held in memory for every job to
+// S3, so none are lost when the launcher shuts down
+func (a *API) FlushProgress(ctx context.Context) {
+	a.progress.mu.Lock()
+	jobIDs := make([]string, 0, len(a.progress.entries))
+	for jobID := range a.progress.entries {
+		jobIDs = append(jobIDs, jobID)
+	}
+	a.progress.mu.Unlock()
+
+	for _, jobID := range jobIDs {
+		a.flushProgress(ctx, jobID)
+	}
+	log.Printf("Flushed the progress of %d jobs", len(jobIDs))
+}
+
+// getProgress returns the progress of a job, preferring events not yet
+// written to S3
+func (a *API) getProgress(ctx context.Context, jobID string) (*types.WorkflowProgress, error) {
+	if entry := a.progress.lookup(jobID); entry != nil {
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+		if entry.progress != nil {
+			progress := *entry.progress
+			progress.Tasks = append([]types.Task(nil), entry.progress.Tasks...)
+			return &progress, nil
+		}
+	}
+	return services.GetWorkflowProgress(ctx, a.s3Client, a.config.JobBucket, jobID)
+}
+
+// JobProgress is the weblog progress of a job, counted per process
+type JobProgress struct {
+	types.WorkflowProgress
+	Total     types.ProcessProgress   `json:"total"`
+	Processes []types.ProcessProgress `json:"processes"`
+}
+
+// @Summary Get job progress
+// @Description Returns the progress of a job's Nextflow run as reported through the weblog, with task counts per process
+// @Produce json
+// @Param   id path string true "Job ID"
+// @Success 200 {object} JobProgress
+// @Router /jobs/{id}/progress [get]
+func (a *API) GetJobProgress(c *gin.Context) {
+	progress, ok := a.jobProgress(c)
+	if !ok {
+		return
+	}
+	response := JobProgress{
+		WorkflowProgress: *progress,
+		Total:            progress.Total(),
+		Processes:        progress.Processes(),
+	}
+	response.Tasks = nil
+	c.JSON(200, response)
+}
+
+// jobProgress loads the progress of the job in the request, responding
+// with an error if there is none
+func (a *API) jobProgress(c *gin.Context) (*types.WorkflowProgress, bool) {
+	ctx := c.Request.Context()
+	job, err := a.jobStore.GetJob(ctx, c.Param("id"))
+	if err != nil {
+		log.Printf("Error getting job spec: %v", err)
+		c.JSON(404, gin.H{"error": "Job not found"})
+		return nil, false
+	}
+	progress, err := a.getProgress(ctx, job.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrProgressNotFound) {
+			c.JSON(404, gin.H{"error": "Job has not reported any progress"})
+			return nil, false
+		}
+		log.Printf("Error getting progress of job %s: %v", job.ID, err)
+		c.JSON(500, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return progress, true
+}
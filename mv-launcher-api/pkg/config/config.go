@@ -4,6 +4,14 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
+)
+
+// Job store backends
+const (
+	JobStoreS3         = "s3"
+	JobStoreFilesystem = "fs"
+	JobStoreSQLite     = "sqlite"
 )
 
 // Config holds all configuration values
@@ -21,6 +29,11 @@ type Config struct {
 	JobBucket      string
 	LogBucket      string
 
+	// Job Store holds job records only. Job groups, schedules, samplesheets,
+	// progress and staged configs stay in JobBucket whichever store is used.
+	JobStore     string
+	JobStorePath string
+
 	// Job Configuration
 	NextflowImage   string
 	NextflowVCPUs   int32
@@ -28,6 +41,25 @@ type Config struct {
 	NextflowWorkDir string
 	NextflowLogPath string
 
+	// Nextflow config rendered for each head node
+	NextflowCLIPath          string
+	NextflowContainerOptions string
+
+	// CloudWatch log group AWS Batch writes container output to
+	BatchLogGroup string
+
+	// URL head nodes reach this API at to post weblog events, empty disables
+	LauncherURL string
+
+	// Default lifetime of presigned download URLs
+	PresignExpiry time.Duration
+
+	// Interval at which job states are synced from AWS Batch, 0 disables
+	ReconcileInterval time.Duration
+
+	// Interval at which schedules are checked for due runs, 0 disables
+	ScheduleInterval time.Duration
+
 	// Server Configuration
 	Port               int
 	CORSAllowedOrigins []string
@@ -49,6 +81,10 @@ func Load() (*Config, error) {
 		JobBucket:      getEnvOrDefault("JOB_BUCKET", ""),
 		LogBucket:      getEnvOrDefault("LOG_BUCKET", ""),
 
+		// Job Store
+		JobStore:     getEnvOrDefault("JOB_STORE", JobStoreS3),
+		JobStorePath: getEnvOrDefault("JOB_STORE_PATH", ""),
+
 		// Job Configuration
 		NextflowImage:   getEnvOrDefault("NEXTFLOW_IMAGE", "achyutha98/nextflow:latest"),
 		NextflowVCPUs:   getEnvInt32OrDefault("NEXTFLOW_VCPUS", 4),
@@ -56,11 +92,31 @@ func Load() (*Config, error) {
 		NextflowWorkDir: getEnvOrDefault("NEXTFLOW_WORK_DIR", "/workspace/work"),
 		NextflowLogPath: getEnvOrDefault("NEXTFLOW_LOG_PATH", "/var/log/nextflow/nextflow.log"),
 
+		// Nextflow config rendered for each head node
+		NextflowCLIPath:          getEnvOrDefault("NEXTFLOW_CLI_PATH", "/nextflow_awscli/bin/aws"),
+		NextflowContainerOptions: getEnvOrDefault("NEXTFLOW_CONTAINER_OPTIONS", "--env MMC_CHECKPOINT_DIAGNOSIS=true --env MMC_CHECKPOINT_IMAGE_SUBPATH=nextflow --env MMC_CHECKPOINT_INTERVAL=5m --env MMC_CHECKPOINT_MODE=true --env MMC_CHECKPOINT_IMAGE_PATH=/mmc-checkpoint"),
+
+		BatchLogGroup: getEnvOrDefault("BATCH_LOG_GROUP", "/aws/batch/job"),
+		LauncherURL:   getEnvOrDefault("LAUNCHER_URL", ""),
+		PresignExpiry: getEnvDurationOrDefault("PRESIGN_EXPIRY", 15*time.Minute),
+
+		ReconcileInterval: getEnvDurationOrDefault("RECONCILE_INTERVAL", 30*time.Second),
+		ScheduleInterval:  getEnvDurationOrDefault("SCHEDULE_INTERVAL", 15*time.Second),
+
 		// Server Configuration
 		Port:               getEnvIntOrDefault("PORT", 8080),
 		CORSAllowedOrigins: getEnvStringSliceOrDefault("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173"}),
 	}
 
+	if config.JobStorePath == "" {
+		switch config.JobStore {
+		case JobStoreFilesystem:
+			config.JobStorePath = "data/jobs"
+		case JobStoreSQLite:
+			config.JobStorePath = "data/jobs.db"
+		}
+	}
+
 	// Validate required fields
 	if err := config.validate(); err != nil {
 		return nil, err
@@ -79,6 +135,12 @@ func (c *Config) validate() error {
 		"LOG_BUCKET":            c.LogBucket,
 	}
 
+	switch c.JobStore {
+	case JobStoreS3, JobStoreFilesystem, JobStoreSQLite:
+	default:
+		return fmt.Errorf("JOB_STORE must be one of %s, %s or %s, got %q", JobStoreS3, JobStoreFilesystem, JobStoreSQLite, c.JobStore)
+	}
+
 	for name, value := range required {
 		if value == "" {
 			return fmt.Errorf("required environment variable %s is not set", name)
@@ -114,6 +176,15 @@ func getEnvInt32OrDefault(key string, defaultValue int32) int32 {
 	return defaultValue
 }
 
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
 func getEnvStringSliceOrDefault(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return []string{value} // For now, just split by comma if needed
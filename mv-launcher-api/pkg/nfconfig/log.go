@@ -0,0 +1,43 @@
+package nfconfig
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+)
+
+// launchRevisionPattern matches the revision Nextflow logs when it launches
+// a pipeline from a repository, e.g.
+// "Launching `https://github.com/nf-core/rnaseq` [happy_pike] DSL2 - revision: 3bec2331ca [3.14.0]"
+var launchRevisionPattern = regexp.MustCompile(`Launching .* revision: ([0-9a-f]{7,40})`)
+
+// CommitIDFile is the file the head node records the full SHA of the commit
+// it ran in, next to nextflow.log
+const CommitIDFile = "commit_id"
+
+// SessionIDFile is the file the head node records its Nextflow session ID
+// in, next to nextflow.log
+const SessionIDFile = "session_id"
+
+// commitSHAPattern matches a full git commit SHA
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// IsCommitSHA reports whether s is a full commit SHA rather than the
+// abbreviated one Nextflow logs, which -r does not accept
+func IsCommitSHA(s string) bool {
+	return commitSHAPattern.MatchString(s)
+}
+
+// ParseCommitID returns the commit a run was launched from, as recorded in
+// its Nextflow log, or "" if the log does not say. Nextflow logs it
+// abbreviated.
+func ParseCommitID(log io.Reader) string {
+	scanner := bufio.NewScanner(log)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if m := launchRevisionPattern.FindStringSubmatch(scanner.Text()); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
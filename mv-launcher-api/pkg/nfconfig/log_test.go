@@ -0,0 +1,33 @@
+package nfconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCommitID(t *testing.T) {
+	log := strings.Join([]string{
+		"Jun-03 10:15:01.123 [main] DEBUG nextflow.cli.Launcher - $> nextflow run nf-core/rnaseq -r 3.14.0",
+		"Jun-03 10:15:04.456 [main] INFO  nextflow.cli.CmdRun - Launching `https://github.com/nf-core/rnaseq` [happy_pike] DSL2 - revision: 3bec2331ca [3.14.0]",
+		"Jun-03 10:15:05.789 [main] DEBUG nextflow.Session - Session UUID: 0b5c1a3e",
+	}, "\n")
+	if got := ParseCommitID(strings.NewReader(log)); got != "3bec2331ca" {
+		t.Errorf("expected commit 3bec2331ca, got %q", got)
+	}
+
+	local := "Jun-03 10:15:04.456 [main] INFO  nextflow.cli.CmdRun - Launching `main.nf` [happy_pike] DSL2 - revision: zzz"
+	if got := ParseCommitID(strings.NewReader(local)); got != "" {
+		t.Errorf("expected no commit for a local script, got %q", got)
+	}
+}
+
+func TestIsCommitSHA(t *testing.T) {
+	if !IsCommitSHA("3bec2331ca4f6e2a1c3b7d9e0f1a2b3c4d5e6f70") {
+		t.Error("expected a full SHA to be accepted")
+	}
+	for _, s := range []string{"3bec2331ca", "3.14.0", "3BEC2331CA4F6E2A1C3B7D9E0F1A2B3C4D5E6F70", ""} {
+		if IsCommitSHA(s) {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package nfconfig
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/MemVerge/nf-launcher/pkg/config"
+	"github.com/MemVerge/nf-launcher/pkg/trace"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+// TaskJobTag is the AWS Batch tag put on every task job a head node submits,
+// so tasks can be traced back to the launcher job that spawned them
+const TaskJobTag = "launcher-job-id"
+
+// ParamsFile is the name the params file of a job is staged under, next to
+// its spec, and passed to Nextflow as
+const ParamsFile = "params.json"
+
+// Config is the typed model of the Nextflow config a head node runs with
+type Config struct {
+	Process ProcessConfig
+	AWS     AWSConfig
+	Trace   TraceConfig
+
+	// AdditionalConfig is appended verbatim after the generated settings
+	AdditionalConfig string
+}
+
+// ProcessConfig holds the process scope used for every task
+type ProcessConfig struct {
+	Executor         string
+	Queue            string
+	MaxRetries       int
+	Memory           string
+	ContainerOptions string
+	ResourceLabels   map[string]string
+}
+
+// AWSConfig holds the aws scope
+type AWSConfig struct {
+	Region string
+	Client AWSClientConfig
+	Batch  AWSBatchConfig
+}
+
+// AWSClientConfig holds the aws.client scope
+type AWSClientConfig struct {
+	MaxConnections     int
+	ConnectionTimeout  int
+	UploadStorageClass string
+	StorageEncryption  string
+}
+
+// AWSBatchConfig holds the aws.batch scope
+type AWSBatchConfig struct {
+	CliPath              string
+	MaxTransferAttempts  int
+	DelayBetweenAttempts string
+}
+
+// TraceConfig holds the trace scope. The head node uploads the trace so
+// the API can list the tasks of a run.
+type TraceConfig struct {
+	File   string
+	Fields []string
+}
+
+// New builds the config model for a job
+func New(job types.Job, cfg *config.Config) Config {
+	return Config{
+		Process: ProcessConfig{
+			Executor:         "awsbatch",
+			Queue:            job.TaskQueue,
+			MaxRetries:       job.MaxRetries,
+			Memory:           job.Memory,
+			ContainerOptions: cfg.NextflowContainerOptions,
+			ResourceLabels: map[string]string{
+				TaskJobTag: job.ID,
+			},
+		},
+		AWS: AWSConfig{
+			Region: cfg.AWSRegion,
+			Client: AWSClientConfig{
+				MaxConnections:     20,
+				ConnectionTimeout:  10000,
+				UploadStorageClass: "INTELLIGENT_TIERING",
+				StorageEncryption:  "AES256",
+			},
+			Batch: AWSBatchConfig{
+				CliPath:              cfg.NextflowCLIPath,
+				MaxTransferAttempts:  3,
+				DelayBetweenAttempts: "5 sec",
+			},
+		},
+		Trace: TraceConfig{
+			File:   trace.FileName,
+			Fields: trace.Fields,
+		},
+		AdditionalConfig: job.AdditionalConfig,
+	}
+}
+
+var configTemplate = template.Must(template.New("nextflow.config").Funcs(template.FuncMap{
+	"quote": quote,
+	"map":   groovyMap,
+	"join":  strings.Join,
+}).Parse(`plugins {
+    id 'nf-amazon'
+}
+process {
+    executor = {{ quote .Process.Executor }}
+    queue = {{ quote .Process.Queue }}
+    maxRetries = {{ .Process.MaxRetries }}
+    memory = {{ quote .Process.Memory }}
+{{- if .Process.ResourceLabels }}
+    resourceLabels = {{ map .Process.ResourceLabels }}
+{{- end }}
+{{- if .Process.ContainerOptions }}
+    containerOptions = {{ quote .Process.ContainerOptions }}
+{{- end }}
+}
+
+aws {
+    region = {{ quote .AWS.Region }}
+    client {
+        maxConnections = {{ .AWS.Client.MaxConnections }}
+        connectionTimeout = {{ .AWS.Client.ConnectionTimeout }}
+        uploadStorageClass = {{ quote .AWS.Client.UploadStorageClass }}
+        storageEncryption = {{ quote .AWS.Client.StorageEncryption }}
+    }
+    batch {
+        cliPath = {{ quote .AWS.Batch.CliPath }}
+        maxTransferAttempts = {{ .AWS.Batch.MaxTransferAttempts }}
+        delayBetweenAttempts = {{ quote .AWS.Batch.DelayBetweenAttempts }}
+    }
+}
+
+trace {
+    enabled = true
+    overwrite = true
+    file = {{ quote .Trace.File }}
+    fields = {{ quote (join .Trace.Fields ",") }}
+}
+{{- if .AdditionalConfig }}
+
+// Additional configuration
+{{ .AdditionalConfig }}
+{{- end }}
+`))
+
+// Render returns the config in Nextflow's Groovy config syntax
+func (c Config) Render() (string, error) {
+	var buf bytes.Buffer
+	if err := configTemplate.Execute(&buf, c); err != nil {
+		return "", fmt.Errorf("failed to render nextflow config: %v", err)
+	}
+	return strings.TrimRight(buf.String(), "\n") + "\n", nil
+}
+
+// quote returns s as a single-quoted Groovy string
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return "'" + s + "'"
+}
+
+// groovyMap returns m as a Groovy map literal with sorted keys
+func groovyMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, quote(key)+": "+quote(m[key]))
+	}
+	return "[" + strings.Join(entries, ", ") + "]"
+}
+
+// Command returns the nextflow command line the head node runs for a job.
+// It mirrors the invocation in run.sh, where the rendered config is
+// downloaded to aws.config.
+func Command(job types.Job, cfg *config.Config) []string {
+	command := []string{"nextflow", "-log", cfg.NextflowLogPath, "run", job.Repo()}
+	if job.Revision != "" {
+		command = append(command, "-r", job.Revision)
+	}
+	if job.Profile != "" {
+		command = append(command, "-profile", job.Profile)
+	}
+	command = append(command,
+		"-work-dir", job.WorkDir,
+		"--outdir", job.ResultDir,
+		"-c", "aws.config",
+		"-ansi-log", "false",
+	)
+	if len(job.Parameters) > 0 {
+		command = append(command, "-params-file", ParamsFile)
+	}
+	if url := WeblogURL(job, cfg); url != "" {
+		command = append(command, "-with-weblog", url)
+	}
+	if job.Resume {
+		command = append(command, "-resume")
+		if job.SessionID != "" {
+			command = append(command, job.SessionID)
+		}
+	}
+	return command
+}
+
+// WeblogURL returns the URL the head node of a job posts Nextflow weblog
+// events to, or "" if no launcher URL is configured
+func WeblogURL(job types.Job, cfg *config.Config) string {
+	if cfg.LauncherURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/v1/jobs/%s/weblog", strings.TrimRight(cfg.LauncherURL, "/"), job.ID)
+}
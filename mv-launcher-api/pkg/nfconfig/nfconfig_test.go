@@ -0,0 +1,87 @@
+package nfconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MemVerge/nf-launcher/pkg/config"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+func TestRender(t *testing.T) {
+	job := types.Job{
+		ID:               "1234",
+		TaskQueue:        "spot-xs",
+		MaxRetries:       3,
+		Memory:           "8G",
+		AdditionalConfig: "params.genome = 'GRCh38'",
+	}
+	cfg := &config.Config{
+		AWSRegion:                "eu-central-1",
+		NextflowCLIPath:          "/opt/aws/bin/aws",
+		NextflowContainerOptions: "--env MMC_CHECKPOINT_MODE=true",
+	}
+
+	rendered, err := New(job, cfg).Render()
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	for _, want := range []string{
+		"executor = 'awsbatch'",
+		"queue = 'spot-xs'",
+		"maxRetries = 3",
+		"memory = '8G'",
+		"resourceLabels = ['launcher-job-id': '1234']",
+		"containerOptions = '--env MMC_CHECKPOINT_MODE=true'",
+		"region = 'eu-central-1'",
+		"cliPath = '/opt/aws/bin/aws'",
+		"file = 'trace.txt'",
+		"fields = 'task_id,hash,native_id,process,",
+		"params.genome = 'GRCh38'",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("rendered config is missing %q:\n%s", want, rendered)
+		}
+	}
+	if strings.Contains(rendered, "us-west-2") {
+		t.Errorf("rendered config should not fall back to us-west-2:\n%s", rendered)
+	}
+}
+
+func TestRenderOmitsEmptyOptionalSettings(t *testing.T) {
+	rendered, err := New(types.Job{ID: "1234"}, &config.Config{}).Render()
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, unwanted := range []string{"containerOptions", "Additional configuration"} {
+		if strings.Contains(rendered, unwanted) {
+			t.Errorf("rendered config should not contain %q:\n%s", unwanted, rendered)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":      "'plain'",
+		"it's":       `'it\'s'`,
+		`C:\path`:    `'C:\\path'`,
+		"two\nlines": `'two\nlines'`,
+	}
+	for in, want := range tests {
+		if got := quote(in); got != want {
+			t.Errorf("quote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestWeblogURL(t *testing.T) {
+	job := types.Job{ID: "1234"}
+	if url := WeblogURL(job, &config.Config{}); url != "" {
+		t.Errorf("expected no weblog without a launcher URL, got %q", url)
+	}
+	cfg := &config.Config{LauncherURL: "https://launcher.example.com/"}
+	if url := WeblogURL(job, cfg); url != "https://launcher.example.com/v1/jobs/1234/weblog" {
+		t.Errorf("unexpected weblog URL %q", url)
+	}
+}
@@ -0,0 +1,282 @@
+package paramschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+// Schema is the subset of a pipeline's nextflow_schema.json the launcher
+// checks parameters against. Parameters may be declared at the top level or
+// in the groups under "definitions" (or "$defs" in newer nf-core templates).
+type Schema struct {
+	Properties map[string]*Property
+	Required   []string
+}
+
+// Property describes a single pipeline parameter
+type Property struct {
+	Type    []string
+	Enum    []interface{}
+	Pattern *regexp.Regexp
+	Minimum *float64
+	Maximum *float64
+	Default interface{}
+}
+
+type rawGroup struct {
+	Properties map[string]rawProperty `json:"properties"`
+	Required   []string               `json:"required"`
+}
+
+type rawSchema struct {
+	rawGroup
+	Definitions map[string]rawGroup `json:"definitions"`
+	Defs        map[string]rawGroup `json:"$defs"`
+}
+
+type rawProperty struct {
+	Type    json.RawMessage `json:"type"`
+	Enum    []interface{}   `json:"enum"`
+	Pattern string          `json:"pattern"`
+	Minimum *float64        `json:"minimum"`
+	Maximum *float64        `json:"maximum"`
+	Default interface{}     `json:"default"`
+}
+
+// Parse reads a nextflow_schema.json document
+func Parse(data []byte) (*Schema, error) {
+	var raw rawSchema
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+
+	s := &Schema{Properties: make(map[string]*Property)}
+	groups := []rawGroup{raw.rawGroup}
+	for _, defs := range []map[string]rawGroup{raw.Definitions, raw.Defs} {
+		names := make([]string, 0, len(defs))
+		for name := range defs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			groups = append(groups, defs[name])
+		}
+	}
+
+	for _, group := range groups {
+		for name, rp := range group.Properties {
+			p, err := newProperty(rp)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema for parameter %s: %v", name, err)
+			}
+			s.Properties[name] = p
+		}
+		s.Required = append(s.Required, group.Required...)
+	}
+	return s, nil
+}
+
+func newProperty(rp rawProperty) (*Property, error) {
+	p := &Property{
+		Enum:    rp.Enum,
+		Minimum: rp.Minimum,
+		Maximum: rp.Maximum,
+		Default: rp.Default,
+	}
+	if len(rp.Type) > 0 {
+		var single string
+		if err := json.Unmarshal(rp.Type, &single); err == nil {
+			p.Type = []string{single}
+		} else if err := json.Unmarshal(rp.Type, &p.Type); err != nil {
+			return nil, fmt.Errorf("type must be a string or a list of strings")
+		}
+	}
+	if rp.Pattern != "" {
+		pattern, err := regexp.Compile(rp.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %v", err)
+		}
+		p.Pattern = pattern
+	}
+	return p, nil
+}
+
+// Validate checks params against the schema and returns them converted to
+// the declared types. Strings given for numbers and booleans are parsed the
+// way Nextflow parses command line parameters. Parameters the schema does
+// not declare are passed through unchecked; values that fail a check are
+// passed through unconverted.
+func (s *Schema) Validate(params map[string]interface{}) (map[string]interface{}, types.ValidationErrors) {
+	var errs types.ValidationErrors
+	converted := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		converted[name] = value
+	}
+
+	for _, name := range s.Required {
+		if value, ok := params[name]; ok && value != nil && value != "" {
+			continue
+		}
+		if p, ok := s.Properties[name]; ok && p.Default != nil {
+			continue
+		}
+		errs.Add("parameters."+name, "is required")
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p, ok := s.Properties[name]
+		if !ok {
+			continue
+		}
+		value, err := p.check(params[name])
+		if err != nil {
+			errs.Add("parameters."+name, "%v", err)
+			continue
+		}
+		converted[name] = value
+	}
+	return converted, errs
+}
+
+// check converts value to the property's type and checks its constraints
+func (p *Property) check(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if len(p.Type) > 0 {
+		var err error
+		for _, typ := range p.Type {
+			var v interface{}
+			if v, err = convert(typ, value); err == nil {
+				value = v
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(p.Enum) > 0 && !p.allows(value) {
+		allowed := make([]string, 0, len(p.Enum))
+		for _, e := range p.Enum {
+			allowed = append(allowed, fmt.Sprint(e))
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+	}
+	if s, ok := value.(string); ok && p.Pattern != nil && !p.Pattern.MatchString(s) {
+		return nil, fmt.Errorf("must match pattern %s", p.Pattern)
+	}
+	if n, ok := toFloat(value); ok {
+		if p.Minimum != nil && n < *p.Minimum {
+			return nil, fmt.Errorf("must be at least %v", *p.Minimum)
+		}
+		if p.Maximum != nil && n > *p.Maximum {
+			return nil, fmt.Errorf("must be at most %v", *p.Maximum)
+		}
+	}
+	return value, nil
+}
+
+func (p *Property) allows(value interface{}) bool {
+	for _, e := range p.Enum {
+		if n, ok := toFloat(value); ok {
+			if m, ok := toFloat(e); ok && n == m {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(value, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// convert returns value as the JSON schema type typ
+func convert(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case "string":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("must be a string")
+	case "integer":
+		switch v := value.(type) {
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n, nil
+			}
+		default:
+			if n, ok := toFloat(v); ok && n == math.Trunc(n) {
+				return int64(n), nil
+			}
+		}
+		return nil, fmt.Errorf("must be an integer")
+	case "number":
+		if s, ok := value.(string); ok {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return n, nil
+			}
+		} else if n, ok := toFloat(value); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("must be a number")
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if strings.EqualFold(v, "true") {
+				return true, nil
+			}
+			if strings.EqualFold(v, "false") {
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("must be true or false")
+	case "object":
+		if v, ok := value.(map[string]interface{}); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("must be an object")
+	case "array":
+		if v, ok := value.([]interface{}); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("must be a list")
+	case "null":
+		return nil, fmt.Errorf("must be null")
+	}
+	// Types this launcher does not know about are left to Nextflow
+	return value, nil
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,148 @@
+package paramschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema",
+  "type": "object",
+  "definitions": {
+    "input_output_options": {
+      "type": "object",
+      "required": ["input", "outdir"],
+      "properties": {
+        "input": {"type": "string", "pattern": "^\\S+\\.csv$"},
+        "outdir": {"type": "string"}
+      }
+    },
+    "alignment_options": {
+      "type": "object",
+      "properties": {
+        "aligner": {"type": "string", "enum": ["star_salmon", "star_rsem", "hisat2"], "default": "star_salmon"},
+        "min_trimmed_reads": {"type": "integer", "minimum": 0, "default": 10000},
+        "skip_trimming": {"type": "boolean"},
+        "pseudo_aligner_kmer_size": {"type": "number"}
+      }
+    }
+  },
+  "allOf": [
+    {"$ref": "#/definitions/input_output_options"},
+    {"$ref": "#/definitions/alignment_options"}
+  ]
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	params, errs := schema.Validate(map[string]interface{}{
+		"input":                    "s3://bucket/samplesheet.csv",
+		"outdir":                   "s3://bucket/results",
+		"aligner":                  "hisat2",
+		"min_trimmed_reads":        "500",
+		"skip_trimming":            "TRUE",
+		"pseudo_aligner_kmer_size": "31.5",
+		"custom_param":             "kept",
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	expected := map[string]interface{}{
+		"input":                    "s3://bucket/samplesheet.csv",
+		"outdir":                   "s3://bucket/results",
+		"aligner":                  "hisat2",
+		"min_trimmed_reads":        int64(500),
+		"skip_trimming":            true,
+		"pseudo_aligner_kmer_size": 31.5,
+		"custom_param":             "kept",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, got %v", expected, params)
+	}
+}
+
+func TestValidateTypedValues(t *testing.T) {
+	schema, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	params, errs := schema.Validate(map[string]interface{}{
+		"input":             "samplesheet.csv",
+		"outdir":            "s3://bucket/results",
+		"min_trimmed_reads": float64(500),
+		"skip_trimming":     false,
+		"genomes":           map[string]interface{}{"GRCh38": map[string]interface{}{"fasta": "s3://refs/GRCh38.fa"}},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if params["min_trimmed_reads"] != int64(500) || params["skip_trimming"] != false {
+		t.Errorf("unexpected conversion: %v", params)
+	}
+	if _, ok := params["genomes"].(map[string]interface{}); !ok {
+		t.Errorf("expected undeclared nested parameters to pass through, got %v", params["genomes"])
+	}
+
+	_, errs = schema.Validate(map[string]interface{}{
+		"input":             "samplesheet.csv",
+		"outdir":            "s3://bucket/results",
+		"min_trimmed_reads": 2.5,
+		"aligner":           true,
+	})
+	if len(errs) != 2 {
+		t.Errorf("expected errors for a fractional integer and a boolean string, got %v", errs)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	schema, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, errs := schema.Validate(map[string]interface{}{
+		"input":             "samplesheet.tsv",
+		"aligner":           "bwa",
+		"min_trimmed_reads": "-1",
+		"skip_trimming":     "yes",
+	})
+	fields := make(map[string]string)
+	for _, e := range errs {
+		fields[e.Field] = e.Message
+	}
+	for _, field := range []string{
+		"parameters.input",
+		"parameters.outdir",
+		"parameters.aligner",
+		"parameters.min_trimmed_reads",
+		"parameters.skip_trimming",
+	} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("expected an error for %s, got %v", field, errs)
+		}
+	}
+	if len(fields) != 5 {
+		t.Errorf("expected 5 field errors, got %v", errs)
+	}
+}
+
+func TestParseDefs(t *testing.T) {
+	schema, err := Parse([]byte(`{
+	  "$defs": {"options": {"properties": {"genome": {"type": ["string", "null"]}}}}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p, ok := schema.Properties["genome"]; !ok || !reflect.DeepEqual(p.Type, []string{"string", "null"}) {
+		t.Errorf("expected genome to be parsed from $defs, got %+v", schema.Properties)
+	}
+
+	if _, err := Parse([]byte(`{"properties": {"x": {"pattern": "("}}}`)); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,141 @@
+package samplesheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+// Sheet is a parsed samplesheet
+type Sheet struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Format returns the format of a samplesheet from its file name
+func Format(fileName string) (string, error) {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".csv":
+		return "csv", nil
+	case ".tsv", ".txt":
+		return "tsv", nil
+	}
+	return "", fmt.Errorf("must be a .csv or .tsv file")
+}
+
+// Parse reads a CSV or TSV samplesheet. Every row must have as many cells
+// as the header.
+func Parse(r io.Reader, format string) (*Sheet, error) {
+	reader := csv.NewReader(r)
+	if format == "tsv" {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("is empty")
+	}
+
+	sheet := &Sheet{Header: trimAll(records[0])}
+	for _, record := range records[1:] {
+		sheet.Rows = append(sheet.Rows, trimAll(record))
+	}
+	if len(sheet.Rows) == 0 {
+		return nil, fmt.Errorf("has a header but no rows")
+	}
+	return sheet, nil
+}
+
+func trimAll(cells []string) []string {
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// Validate checks the sheet against a column spec and returns the S3 URIs
+// its file columns reference. Without a spec, every cell holding an S3 URI
+// is taken as a file reference. Rows are numbered from 1, not counting the
+// header.
+func (s *Sheet) Validate(spec *types.SamplesheetSpec) ([]string, types.ValidationErrors) {
+	var errs types.ValidationErrors
+	columns := make(map[string]int, len(s.Header))
+	for i, name := range s.Header {
+		if name == "" {
+			errs.Add("header", "column %d has no name", i+1)
+			continue
+		}
+		if _, ok := columns[name]; ok {
+			errs.Add("header", "column %s appears more than once", name)
+			continue
+		}
+		columns[name] = i
+	}
+
+	files := make([]string, 0)
+	seen := make(map[string]bool)
+	addFile := func(uri string) {
+		if !seen[uri] {
+			seen[uri] = true
+			files = append(files, uri)
+		}
+	}
+
+	if spec == nil {
+		for _, row := range s.Rows {
+			for _, cell := range row {
+				if strings.HasPrefix(cell, "s3://") {
+					addFile(cell)
+				}
+			}
+		}
+		return files, errs
+	}
+
+	for _, col := range spec.Columns {
+		idx, ok := columns[col.Name]
+		if !ok {
+			if col.Required {
+				errs.Add("header", "missing required column %s", col.Name)
+			}
+			continue
+		}
+
+		var pattern *regexp.Regexp
+		if col.Pattern != "" {
+			// Specs are checked when pipelines are registered
+			pattern, _ = regexp.Compile(col.Pattern)
+		}
+		for i, row := range s.Rows {
+			field := fmt.Sprintf("rows[%d].%s", i+1, col.Name)
+			value := row[idx]
+			if value == "" {
+				if col.Required {
+					errs.Add(field, "is required")
+				}
+				continue
+			}
+			if pattern != nil && !pattern.MatchString(value) {
+				errs.Add(field, "must match pattern %s", col.Pattern)
+				continue
+			}
+			if col.File {
+				if !strings.HasPrefix(value, "s3://") || types.S3Bucket(value) == "" {
+					errs.Add(field, "must be an s3:// URI")
+					continue
+				}
+				addFile(value)
+			}
+		}
+	}
+	return files, errs
+}
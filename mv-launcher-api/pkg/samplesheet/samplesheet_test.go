@@ -0,0 +1,83 @@
+package samplesheet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+var rnaseqSpec = &types.SamplesheetSpec{
+	Columns: []types.SamplesheetColumn{
+		{Name: "sample", Required: true, Pattern: `^\S+$`},
+		{Name: "fastq_1", Required: true, File: true},
+		{Name: "fastq_2", File: true},
+		{Name: "strandedness", Required: true, Pattern: `^(forward|reverse|unstranded|auto)$`},
+	},
+}
+
+func TestParseAndValidate(t *testing.T) {
+	csv := `sample,fastq_1,fastq_2,strandedness
+CONTROL_1,s3://reads/ctrl_1_R1.fastq.gz,s3://reads/ctrl_1_R2.fastq.gz,auto
+CONTROL_1,s3://reads/ctrl_1b_R1.fastq.gz,,auto
+`
+	sheet, err := Parse(strings.NewReader(csv), "csv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, errs := sheet.Validate(rnaseqSpec)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	expected := []string{"s3://reads/ctrl_1_R1.fastq.gz", "s3://reads/ctrl_1b_R1.fastq.gz", "s3://reads/ctrl_1_R2.fastq.gz"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tsv := "sample\tfastq_1\n" +
+		"bad sample\tfastq/local.fastq.gz\n" +
+		"\ts3://reads/x.fastq.gz\n"
+	sheet, err := Parse(strings.NewReader(tsv), "tsv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, errs := sheet.Validate(rnaseqSpec)
+	fields := make(map[string]bool)
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, field := range []string{"header", "rows[1].sample", "rows[1].fastq_1", "rows[2].sample"} {
+		if !fields[field] {
+			t.Errorf("expected an error for %s, got %v", field, errs)
+		}
+	}
+}
+
+func TestValidateWithoutSpec(t *testing.T) {
+	sheet, err := Parse(strings.NewReader("id,path\na,s3://b/a.txt\nb,local.txt\n"), "csv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, errs := sheet.Validate(nil)
+	if len(errs) > 0 || !reflect.DeepEqual(files, []string{"s3://b/a.txt"}) {
+		t.Errorf("unexpected result %v, %v", files, errs)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"empty":     "",
+		"no rows":   "sample,fastq_1\n",
+		"bad width": "sample,fastq_1\na\n",
+	} {
+		if _, err := Parse(strings.NewReader(input), "csv"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := Format("samplesheet.xlsx"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrArtifactNotFound is returned when a job has not uploaded a file
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// CacheArtifactsPrefix is where the head node keeps the .nextflow cache of
+// a job, relative to its log prefix. It holds Nextflow's internal state
+// rather than results.
+const CacheArtifactsPrefix = "nextflow-cache/"
+
+// artifactsPrefix returns the S3 prefix a job's head node uploads to
+func artifactsPrefix(jobID string) string {
+	return fmt.Sprintf("jobs/%s/", jobID)
+}
+
+// ArtifactKey returns the S3 key of a file a job's head node uploaded
+func ArtifactKey(jobID string, name string) string {
+	return artifactsPrefix(jobID) + name
+}
+
+// ListJobArtifacts lists the files under a job's log prefix, sorted by
+// name. The .nextflow cache is left out unless includeCache is set.
+func ListJobArtifacts(ctx context.Context, s3Client *s3.Client, bucket string, jobID string, includeCache bool) ([]types.Artifact, error) {
+	prefix := artifactsPrefix(jobID)
+	artifacts := make([]types.Artifact, 0)
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %v", err)
+		}
+		for _, item := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(item.Key), prefix)
+			if name == "" || strings.HasSuffix(name, "/") {
+				continue
+			}
+			if !includeCache && strings.HasPrefix(name, CacheArtifactsPrefix) {
+				continue
+			}
+			artifacts = append(artifacts, types.Artifact{
+				Name:         name,
+				Key:          aws.ToString(item.Key),
+				Size:         aws.ToInt64(item.Size),
+				Type:         ArtifactType(name),
+				LastModified: aws.ToTime(item.LastModified),
+			})
+		}
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Name < artifacts[j].Name })
+	return artifacts, nil
+}
+
+// GetJobArtifact describes a single file a job's head node uploaded,
+// without downloading it
+func GetJobArtifact(ctx context.Context, s3Client *s3.Client, bucket string, jobID string, name string) (*types.Artifact, error) {
+	key := ArtifactKey(jobID, name)
+	result, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *s3types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrArtifactNotFound
+		}
+		return nil, fmt.Errorf("failed to get %s for job %s from S3: %v", name, jobID, err)
+	}
+	return &types.Artifact{
+		Name:         name,
+		Key:          key,
+		Size:         aws.ToInt64(result.ContentLength),
+		Type:         ArtifactType(name),
+		LastModified: aws.ToTime(result.LastModified),
+	}, nil
+}
+
+// ArtifactType guesses the media type of an artifact from its name. The
+// head node uploads without a content type, so S3 cannot tell us.
+func ArtifactType(name string) string {
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".log", ".txt", ".tsv", ".nf", ".config", "":
+		return "text/plain; charset=utf-8"
+	default:
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t
+		}
+	}
+	return "application/octet-stream"
+}
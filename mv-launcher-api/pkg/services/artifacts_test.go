@@ -0,0 +1,20 @@
+package services
+
+import "testing"
+
+func TestArtifactType(t *testing.T) {
+	tests := map[string]string{
+		"nextflow.log":       "text/plain; charset=utf-8",
+		"trace.txt":          "text/plain; charset=utf-8",
+		"session_id":         "text/plain; charset=utf-8",
+		"report.html":        "text/html; charset=utf-8",
+		"pipeline_dag.HTML":  "text/html; charset=utf-8",
+		"progress.json":      "application/json",
+		"results.unknownext": "application/octet-stream",
+	}
+	for name, want := range tests {
+		if got := ArtifactType(name); got != want {
+			t.Errorf("ArtifactType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+// jobReferencePattern matches references to other jobs in parameter
+// values, such as {{jobs.<id>.result_dir}}
+var jobReferencePattern = regexp.MustCompile(`\{\{\s*jobs\.([A-Za-z0-9_-]+)\.([a-z_]+)\s*\}\}`)
+
+// jobReferenceFields are the job fields parameters may refer to. They are
+// all known when a job is created, so references resolve at submission.
+var jobReferenceFields = map[string]func(types.Job) string{
+	"id":         func(j types.Job) string { return j.ID },
+	"name":       func(j types.Job) string { return j.Name },
+	"pipeline":   func(j types.Job) string { return j.Pipeline },
+	"revision":   func(j types.Job) string { return j.Revision },
+	"work_dir":   func(j types.Job) string { return j.WorkDir },
+	"result_dir": func(j types.Job) string { return j.ResultDir },
+	"log_bucket": func(j types.Job) string { return j.LogBucket },
+}
+
+// JobReferences returns the IDs of the jobs the parameters refer to, in
+// order
+func JobReferences(params map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	walkStrings(params, func(s string) string {
+		for _, m := range jobReferencePattern.FindAllStringSubmatch(s, -1) {
+			seen[m[1]] = true
+		}
+		return s
+	})
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ResolveJobReferences returns a copy of the parameters with every
+// reference to another job replaced by the referenced field
+func ResolveJobReferences(params map[string]interface{}, jobs map[string]*types.Job) (map[string]interface{}, error) {
+	var resolveErr error
+	resolved := walkStrings(params, func(s string) string {
+		return jobReferencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+			m := jobReferencePattern.FindStringSubmatch(ref)
+			job, ok := jobs[m[1]]
+			if !ok {
+				resolveErr = fmt.Errorf("refers to unknown job %s", m[1])
+				return ref
+			}
+			field, ok := jobReferenceFields[m[2]]
+			if !ok {
+				resolveErr = fmt.Errorf("refers to unsupported field %s of job %s", m[2], m[1])
+				return ref
+			}
+			return field(*job)
+		})
+	})
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	return resolved.(map[string]interface{}), nil
+}
+
+// walkStrings copies a decoded JSON value, passing every string in it
+// through fn
+func walkStrings(value interface{}, fn func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			copied[k] = walkStrings(item, fn)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = walkStrings(item, fn)
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+func TestResolveJobReferences(t *testing.T) {
+	params := map[string]interface{}{
+		"input":   "{{jobs.fetch-1.result_dir}}/samplesheet/samplesheet.csv",
+		"genome":  "GRCh38",
+		"reports": []interface{}{"{{ jobs.fetch-1.name }}", float64(3)},
+		"nested":  map[string]interface{}{"work": "{{jobs.qc-2.work_dir}}"},
+	}
+	if refs := JobReferences(params); !reflect.DeepEqual(refs, []string{"fetch-1", "qc-2"}) {
+		t.Errorf("unexpected references %v", refs)
+	}
+
+	jobs := map[string]*types.Job{
+		"fetch-1": {ID: "fetch-1", Name: "fetchngs", ResultDir: "s3://results/fetch"},
+		"qc-2":    {ID: "qc-2", WorkDir: "s3://work/qc"},
+	}
+	resolved, err := ResolveJobReferences(params, jobs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"input":   "s3://results/fetch/samplesheet/samplesheet.csv",
+		"genome":  "GRCh38",
+		"reports": []interface{}{"fetchngs", float64(3)},
+		"nested":  map[string]interface{}{"work": "s3://work/qc"},
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("expected %v, got %v", expected, resolved)
+	}
+	if params["input"] != "{{jobs.fetch-1.result_dir}}/samplesheet/samplesheet.csv" {
+		t.Error("resolving must not modify the parameters")
+	}
+
+	if _, err := ResolveJobReferences(map[string]interface{}{"x": "{{jobs.fetch-1.status}}"}, jobs); err == nil {
+		t.Error("expected an error for an unsupported field")
+	}
+	if _, err := ResolveJobReferences(map[string]interface{}{"x": "{{jobs.other.result_dir}}"}, jobs); err == nil {
+		t.Error("expected an error for an unknown job")
+	}
+}
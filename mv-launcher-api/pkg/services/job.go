@@ -4,59 +4,253 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/MemVerge/nf-launcher/pkg/config"
 	"github.com/MemVerge/nf-launcher/pkg/types"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sirupsen/logrus"
 )
 
-// GetJobs retrieves all jobs from S3
-func GetJobs(s3Client *s3.Client, bucket string) (types.Jobs, error) {
-	result, err := s3Client.ListObjects(context.TODO(), &s3.ListObjectsInput{
-		Bucket: aws.String(bucket),
-		Prefix: aws.String("jobs/"),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list objects: %v", err)
+// ErrJobNotFound is returned by a JobStore when a job does not exist
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStore persists job records
+type JobStore interface {
+	// GetJobs returns every stored job
+	GetJobs(ctx context.Context) (types.Jobs, error)
+	// QueryJobs returns the stored jobs matching a query
+	QueryJobs(ctx context.Context, query JobQuery) (types.Jobs, error)
+	// GetJob returns a single job, or ErrJobNotFound
+	GetJob(ctx context.Context, jobID string) (*types.Job, error)
+	// PutJob creates or replaces a job
+	PutJob(ctx context.Context, job types.Job) error
+}
+
+// JobQuery selects stored jobs. Empty fields match every job.
+type JobQuery struct {
+	Status        string
+	Pipeline      string
+	User          string
+	GroupID       string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// Matches reports whether a job satisfies the query
+func (q JobQuery) Matches(job types.Job) bool {
+	if q.Status != "" && job.Status != q.Status {
+		return false
+	}
+	if q.Pipeline != "" && job.Pipeline != q.Pipeline {
+		return false
 	}
+	if q.User != "" && job.User != q.User {
+		return false
+	}
+	if q.GroupID != "" && job.GroupID != q.GroupID {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && !job.CreatedAt.After(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !job.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	return true
+}
 
-	jobs := make(types.Jobs, 0)
-	for _, item := range result.Contents {
-		if *item.Key == "jobs/" {
-			continue
+// filterJobs returns the jobs matching a query
+func filterJobs(jobs types.Jobs, query JobQuery) types.Jobs {
+	matched := make(types.Jobs, 0, len(jobs))
+	for _, job := range jobs {
+		if query.Matches(job) {
+			matched = append(matched, job)
 		}
+	}
+	return matched
+}
 
-		objInput := &s3.GetObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(*item.Key),
-		}
+// NewJobStore creates the job store selected in the configuration
+func NewJobStore(cfg *config.Config, s3Client *s3.Client) (JobStore, error) {
+	switch cfg.JobStore {
+	case config.JobStoreS3:
+		return NewS3JobStore(s3Client, cfg.JobBucket), nil
+	case config.JobStoreFilesystem:
+		return NewFSJobStore(cfg.JobStorePath)
+	case config.JobStoreSQLite:
+		return NewSQLiteJobStore(cfg.JobStorePath)
+	default:
+		return nil, fmt.Errorf("unknown job store %q", cfg.JobStore)
+	}
+}
 
-		objResult, err := s3Client.GetObject(context.Background(), objInput)
+// s3FetchWorkers bounds the number of concurrent GetObject calls when
+// listing jobs
+const s3FetchWorkers = 16
+
+// S3JobStore keeps each job as jobs/<id>/job.json in a bucket. Decoded jobs
+// are cached by ETag so repeated listings only fetch what changed.
+type S3JobStore struct {
+	s3Client *s3.Client
+	bucket   string
+
+	mu    sync.Mutex
+	cache map[string]cachedJob
+}
+
+// cachedJob is a decoded job.json and the ETag it was read at
+type cachedJob struct {
+	etag string
+	job  types.Job
+}
+
+// NewS3JobStore creates a job store backed by an S3 bucket
+func NewS3JobStore(s3Client *s3.Client, bucket string) *S3JobStore {
+	return &S3JobStore{
+		s3Client: s3Client,
+		bucket:   bucket,
+		cache:    make(map[string]cachedJob),
+	}
+}
+
+// jobKey returns the S3 key of a job
+func jobKey(jobID string) string {
+	return fmt.Sprintf("jobs/%s/job.json", jobID)
+}
+
+// GetJobs retrieves all jobs from S3
+func (s *S3JobStore) GetJobs(ctx context.Context) (types.Jobs, error) {
+	// List every jobs/<id>/job.json, skipping the logs and other files kept
+	// next to it
+	objects := make([]s3types.Object, 0)
+	paginator := s3.NewListObjectsV2Paginator(s.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String("jobs/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+		for _, item := range page.Contents {
+			if strings.Count(*item.Key, "/") == 2 && strings.HasSuffix(*item.Key, "/job.json") {
+				objects = append(objects, item)
+			}
 		}
+	}
 
-		defer objResult.Body.Close()
-		var job types.Job
-		if err := json.NewDecoder(objResult.Body).Decode(&job); err != nil {
+	// Serve unchanged jobs from the cache and fetch the rest
+	jobs := make(types.Jobs, 0, len(objects))
+	stale := make([]s3types.Object, 0)
+	listed := make(map[string]bool, len(objects))
+	s.mu.Lock()
+	for _, item := range objects {
+		listed[*item.Key] = true
+		if cached, ok := s.cache[*item.Key]; ok && cached.etag == aws.ToString(item.ETag) {
+			jobs = append(jobs, cached.job)
 			continue
 		}
-		jobs = append(jobs, job)
+		stale = append(stale, item)
+	}
+	// Forget jobs that were deleted from the bucket
+	for key := range s.cache {
+		if !listed[key] {
+			delete(s.cache, key)
+		}
+	}
+	s.mu.Unlock()
+
+	fetched, err := s.fetchJobs(ctx, stale)
+	if err != nil {
+		return nil, err
 	}
+	jobs = append(jobs, fetched...)
+	logrus.Debugf("Listed %d jobs, fetched %d from S3", len(jobs), len(fetched))
 	return jobs, nil
 }
 
-// GetJob retrieves a job from S3
-func GetJob(s3Client *s3.Client, bucket string, jobID string) (*types.Job, error) {
-	getObjectInput := &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(fmt.Sprintf("jobs/%s/job.json", jobID)),
+// fetchJobs downloads and decodes job objects with a bounded worker pool.
+// Jobs deleted since they were listed are skipped; any other failure fails
+// the whole fetch, so callers never act on a partial list.
+func (s *S3JobStore) fetchJobs(ctx context.Context, objects []s3types.Object) (types.Jobs, error) {
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan string)
+	results := make(chan types.Job)
+	var (
+		errMu    sync.Mutex
+		fetchErr error
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(s3FetchWorkers, len(objects)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				job, err := s.getJobObject(fetchCtx, key)
+				var noSuchKey *s3types.NoSuchKey
+				if errors.As(err, &noSuchKey) {
+					continue
+				}
+				if err != nil {
+					errMu.Lock()
+					if fetchErr == nil {
+						fetchErr = fmt.Errorf("failed to get job object %s: %v", key, err)
+					}
+					errMu.Unlock()
+					cancel()
+					continue
+				}
+				results <- *job
+			}
+		}()
+	}
+	go func() {
+		defer close(keys)
+		for _, item := range objects {
+			select {
+			case keys <- *item.Key:
+			case <-fetchCtx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	jobs := make(types.Jobs, 0, len(objects))
+	for job := range results {
+		jobs = append(jobs, job)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
 	}
+	return jobs, nil
+}
 
-	result, err := s3Client.GetObject(context.TODO(), getObjectInput)
+// getJobObject downloads and decodes a job object and caches it by ETag
+func (s *S3JobStore) getJobObject(ctx context.Context, key string) (*types.Job, error) {
+	result, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get job from S3: %v", err)
+		return nil, err
 	}
 	defer result.Body.Close()
 
@@ -65,11 +259,37 @@ func GetJob(s3Client *s3.Client, bucket string, jobID string) (*types.Job, error
 		return nil, fmt.Errorf("failed to decode job: %v", err)
 	}
 
+	s.mu.Lock()
+	s.cache[key] = cachedJob{etag: aws.ToString(result.ETag), job: job}
+	s.mu.Unlock()
 	return &job, nil
 }
 
+// QueryJobs filters the jobs in S3. S3 has no secondary indexes, so this
+// reads every job.
+func (s *S3JobStore) QueryJobs(ctx context.Context, query JobQuery) (types.Jobs, error) {
+	jobs, err := s.GetJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterJobs(jobs, query), nil
+}
+
+// GetJob retrieves a job from S3
+func (s *S3JobStore) GetJob(ctx context.Context, jobID string) (*types.Job, error) {
+	job, err := s.getJobObject(ctx, jobKey(jobID))
+	if err != nil {
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get job from S3: %v", err)
+	}
+	return job, nil
+}
+
 // PutJob stores a job in S3
-func PutJob(s3Client *s3.Client, bucket string, job types.Job) error {
+func (s *S3JobStore) PutJob(ctx context.Context, job types.Job) error {
 	// Convert job to JSON
 	jobJSON, err := json.Marshal(job)
 	if err != nil {
@@ -77,16 +297,53 @@ func PutJob(s3Client *s3.Client, bucket string, job types.Job) error {
 	}
 
 	// Upload to S3
+	key := jobKey(job.ID)
 	putObjectInput := &s3.PutObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(fmt.Sprintf("jobs/%s/job.json", job.ID)),
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
 		Body:   bytes.NewReader(jobJSON),
 	}
 
-	_, err = s3Client.PutObject(context.TODO(), putObjectInput)
+	result, err := s.s3Client.PutObject(ctx, putObjectInput)
 	if err != nil {
 		return fmt.Errorf("failed to put job in S3: %v", err)
 	}
 
+	s.mu.Lock()
+	s.cache[key] = cachedJob{etag: aws.ToString(result.ETag), job: job}
+	s.mu.Unlock()
+	return nil
+}
+
+// PutJobFile stores a file the head node of a job needs next to its spec,
+// as jobs/<id>/<name>
+func PutJobFile(ctx context.Context, s3Client *s3.Client, bucket string, jobID string, name string, body []byte) error {
+	_, err := s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(fmt.Sprintf("jobs/%s/%s", jobID, name)),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put %s for job %s in S3: %v", name, jobID, err)
+	}
 	return nil
 }
+
+// GetJobLogFile reads a small text file the head node of a job uploaded next
+// to its logs, as jobs/<id>/<name>
+func GetJobLogFile(ctx context.Context, s3Client *s3.Client, bucket string, jobID string, name string) (string, error) {
+	result, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(fmt.Sprintf("jobs/%s/%s", jobID, name)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s from S3: %v", name, err)
+	}
+	defer result.Body.Close()
+
+	body, err := io.ReadAll(result.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
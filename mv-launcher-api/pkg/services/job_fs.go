@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+// FSJobStore keeps each job as <dir>/<id>/job.json on the local filesystem,
+// mirroring the S3 layout
+type FSJobStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFSJobStore creates a job store rooted at dir
+func NewFSJobStore(dir string) (*FSJobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %v", err)
+	}
+	return &FSJobStore{dir: dir}, nil
+}
+
+// validJobID reports whether a job ID names a single directory in the store
+func validJobID(jobID string) bool {
+	return jobID != "" && jobID != "." && jobID != ".." && filepath.Base(jobID) == jobID
+}
+
+func (s *FSJobStore) jobPath(jobID string) string {
+	return filepath.Join(s.dir, jobID, "job.json")
+}
+
+// GetJobs reads all jobs from disk
+func (s *FSJobStore) GetJobs(ctx context.Context) (types.Jobs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store directory: %v", err)
+	}
+
+	jobs := make(types.Jobs, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		job, err := s.readJob(entry.Name())
+		// Directories without a job.json are not jobs
+		if errors.Is(err, ErrJobNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job %s: %v", entry.Name(), err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// QueryJobs filters the jobs on disk
+func (s *FSJobStore) QueryJobs(ctx context.Context, query JobQuery) (types.Jobs, error) {
+	jobs, err := s.GetJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterJobs(jobs, query), nil
+}
+
+// GetJob reads a job from disk
+func (s *FSJobStore) GetJob(ctx context.Context, jobID string) (*types.Job, error) {
+	if !validJobID(jobID) {
+		return nil, ErrJobNotFound
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.readJob(jobID)
+}
+
+func (s *FSJobStore) readJob(jobID string) (*types.Job, error) {
+	data, err := os.ReadFile(s.jobPath(jobID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to read job: %v", err)
+	}
+
+	var job types.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %v", err)
+	}
+	return &job, nil
+}
+
+// PutJob writes a job to disk. The file is replaced atomically so readers
+// never see a partial job.
+func (s *FSJobStore) PutJob(ctx context.Context, job types.Job) error {
+	if !validJobID(job.ID) {
+		return fmt.Errorf("invalid job id %q", job.ID)
+	}
+
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.jobPath(job.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create job directory: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, jobJSON, 0o644); err != nil {
+		return fmt.Errorf("failed to write job: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write job: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema stores the full job as JSON and copies the queryable fields
+// into indexed columns
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id         TEXT PRIMARY KEY,
+	status     TEXT NOT NULL DEFAULT '',
+	pipeline   TEXT NOT NULL DEFAULT '',
+	user       TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL DEFAULT 0,
+	data       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS jobs_status ON jobs (status);
+CREATE INDEX IF NOT EXISTS jobs_pipeline ON jobs (pipeline);
+CREATE INDEX IF NOT EXISTS jobs_user ON jobs (user);
+CREATE INDEX IF NOT EXISTS jobs_created_at ON jobs (created_at);
+`
+
+// SQLiteJobStore keeps jobs in an embedded SQLite database
+type SQLiteJobStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteJobStore opens or creates the database at path
+func NewSQLiteJobStore(path string) (*SQLiteJobStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job database: %v", err)
+	}
+	// SQLite allows a single writer
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create job schema: %v", err)
+	}
+	return &SQLiteJobStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *SQLiteJobStore) Close() error {
+	return s.db.Close()
+}
+
+// GetJobs reads all jobs from the database
+func (s *SQLiteJobStore) GetJobs(ctx context.Context) (types.Jobs, error) {
+	return s.QueryJobs(ctx, JobQuery{})
+}
+
+// QueryJobs selects jobs using the indexed columns
+func (s *SQLiteJobStore) QueryJobs(ctx context.Context, query JobQuery) (types.Jobs, error) {
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	if query.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, query.Status)
+	}
+	if query.Pipeline != "" {
+		where = append(where, "pipeline = ?")
+		args = append(args, query.Pipeline)
+	}
+	if query.User != "" {
+		where = append(where, "user = ?")
+		args = append(args, query.User)
+	}
+	if query.GroupID != "" {
+		where = append(where, "json_extract(data, '$.group_id') = ?")
+		args = append(args, query.GroupID)
+	}
+	if !query.CreatedAfter.IsZero() {
+		where = append(where, "created_at > ?")
+		args = append(args, query.CreatedAfter.UnixNano())
+	}
+	if !query.CreatedBefore.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, query.CreatedBefore.UnixNano())
+	}
+
+	stmt := "SELECT data FROM jobs"
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += " ORDER BY created_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %v", err)
+	}
+	defer rows.Close()
+
+	jobs := make(types.Jobs, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read job: %v", err)
+		}
+		var job types.Job
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %v", err)
+	}
+	return jobs, nil
+}
+
+// GetJob reads a job from the database
+func (s *SQLiteJobStore) GetJob(ctx context.Context, jobID string) (*types.Job, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM jobs WHERE id = ?", jobID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get job: %v", err)
+	}
+
+	var job types.Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %v", err)
+	}
+	return &job, nil
+}
+
+// PutJob inserts or replaces a job
+func (s *SQLiteJobStore) PutJob(ctx context.Context, job types.Job) error {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	createdAt := int64(0)
+	if !job.CreatedAt.IsZero() {
+		createdAt = job.CreatedAt.UnixNano()
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO jobs (id, status, pipeline, user, created_at, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			pipeline = excluded.pipeline,
+			user = excluded.user,
+			created_at = excluded.created_at,
+			data = excluded.data`,
+		job.ID, job.Status, job.Pipeline, job.User, createdAt, string(jobJSON))
+	if err != nil {
+		return fmt.Errorf("failed to put job: %v", err)
+	}
+	return nil
+}
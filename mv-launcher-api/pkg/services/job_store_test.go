@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+func TestFSJobStore(t *testing.T) {
+	store, err := NewFSJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSJobStore: %v", err)
+	}
+	testJobStore(t, store)
+
+	// IDs are never resolved outside the store
+	for _, id := range []string{"", ".", "..", "../a", "a/../b"} {
+		if _, err := store.GetJob(context.Background(), id); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("GetJob(%q) error = %v, want ErrJobNotFound", id, err)
+		}
+		if err := store.PutJob(context.Background(), types.Job{ID: id}); err == nil {
+			t.Errorf("PutJob(%q) expected an error", id)
+		}
+	}
+}
+
+func TestSQLiteJobStore(t *testing.T) {
+	store, err := NewSQLiteJobStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteJobStore: %v", err)
+	}
+	defer store.Close()
+	testJobStore(t, store)
+}
+
+func testJobStore(t *testing.T, store JobStore) {
+	ctx := context.Background()
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	jobs := types.Jobs{
+		{ID: "a", Pipeline: "nf-core/rnaseq", User: "alice", Status: "SUCCEEDED", CreatedAt: day},
+		{ID: "b", Pipeline: "nf-core/rnaseq", User: "bob", Status: "RUNNING", CreatedAt: day.Add(24 * time.Hour)},
+		{ID: "c", Pipeline: "nf-core/sarek", User: "alice", Status: "FAILED", GroupID: "g", CreatedAt: day.Add(48 * time.Hour)},
+	}
+	for _, job := range jobs {
+		if err := store.PutJob(ctx, job); err != nil {
+			t.Fatalf("PutJob(%s): %v", job.ID, err)
+		}
+	}
+
+	if _, err := store.GetJob(ctx, "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("GetJob(missing) error = %v, want ErrJobNotFound", err)
+	}
+
+	// Updates replace the stored job
+	updated := jobs[1]
+	updated.Status = "SUCCEEDED"
+	if err := store.PutJob(ctx, updated); err != nil {
+		t.Fatalf("PutJob(update): %v", err)
+	}
+	got, err := store.GetJob(ctx, "b")
+	if err != nil {
+		t.Fatalf("GetJob(b): %v", err)
+	}
+	if got.Status != "SUCCEEDED" || got.User != "bob" {
+		t.Errorf("GetJob(b) = %+v, want updated job", got)
+	}
+
+	all, err := store.GetJobs(ctx)
+	if err != nil {
+		t.Fatalf("GetJobs: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("GetJobs returned %d jobs, want 3", len(all))
+	}
+
+	tests := []struct {
+		name  string
+		query JobQuery
+		want  []string
+	}{
+		{"status", JobQuery{Status: "SUCCEEDED"}, []string{"a", "b"}},
+		{"pipeline", JobQuery{Pipeline: "nf-core/sarek"}, []string{"c"}},
+		{"user", JobQuery{User: "alice"}, []string{"a", "c"}},
+		{"created after", JobQuery{CreatedAfter: day}, []string{"b", "c"}},
+		{"created before", JobQuery{CreatedBefore: day.Add(48 * time.Hour)}, []string{"a", "b"}},
+		{"group", JobQuery{GroupID: "g"}, []string{"c"}},
+		{"combined", JobQuery{User: "alice", Status: "FAILED"}, []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := store.QueryJobs(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("QueryJobs: %v", err)
+			}
+			ids := make(map[string]bool)
+			for _, job := range matched {
+				ids[job.ID] = true
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("QueryJobs returned %v, want %v", ids, tt.want)
+			}
+			for _, id := range tt.want {
+				if !ids[id] {
+					t.Errorf("QueryJobs missing job %s, got %v", id, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryJobsByRegistryName(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFSJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSJobStore: %v", err)
+	}
+
+	// A job submitted by registry name is listed under that name
+	pipeline := types.Pipeline{Name: "rnaseq", SourceRepo: "nf-core/rnaseq"}
+	job := types.Job{ID: "a", Pipeline: "rnaseq"}
+	var errs types.ValidationErrors
+	pipeline.ApplyTo(&job, &errs)
+	if err := store.PutJob(ctx, job); err != nil {
+		t.Fatalf("PutJob: %v", err)
+	}
+	if err := store.PutJob(ctx, types.Job{ID: "b", Pipeline: "nf-core/rnaseq"}); err != nil {
+		t.Fatalf("PutJob: %v", err)
+	}
+
+	matched, err := store.QueryJobs(ctx, JobQuery{Pipeline: "rnaseq"})
+	if err != nil {
+		t.Fatalf("QueryJobs: %v", err)
+	}
+	if len(matched) != 1 || matched[0].ID != "a" || matched[0].Repo() != "nf-core/rnaseq" {
+		t.Errorf("expected job a running nf-core/rnaseq, got %+v", matched)
+	}
+}
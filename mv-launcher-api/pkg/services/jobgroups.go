@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrJobGroupNotFound is returned when a job group does not exist
+var ErrJobGroupNotFound = errors.New("job group not found")
+
+// jobGroupKey returns the S3 key of a job group
+func jobGroupKey(id string) string {
+	return fmt.Sprintf("job-groups/%s.json", id)
+}
+
+// ExpandJobGroup builds the child jobs of a group from its template and
+// either its overrides or its sweep. Children are numbered after the group
+// unless an override names them.
+func ExpandJobGroup(group types.JobGroup) ([]types.Job, error) {
+	var errs types.ValidationErrors
+	switch {
+	case len(group.Overrides) > 0 && len(group.Sweep) > 0:
+		errs.Add("sweep", "cannot be combined with overrides")
+	case len(group.Overrides) == 0 && len(group.Sweep) == 0:
+		errs.Add("overrides", "either overrides or sweep is required")
+	case len(group.Overrides) > types.MaxJobGroupSize:
+		errs.Add("overrides", "must not list more than %d jobs", types.MaxJobGroupSize)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	baseName := group.Name
+	if baseName == "" {
+		baseName = group.Template.Name
+	}
+	template := group.Template
+	template.GroupID = group.ID
+
+	var children []types.Job
+	if len(group.Overrides) > 0 {
+		for i, patch := range group.Overrides {
+			child, err := ApplyJobPatch(template, patch)
+			if err != nil {
+				errs.Add(fmt.Sprintf("overrides[%d]", i), "%v", err)
+				continue
+			}
+			children = append(children, *child)
+		}
+	} else {
+		combinations, err := sweepCombinations(group.Sweep)
+		if err != nil {
+			return nil, err
+		}
+		for _, params := range combinations {
+			child := template
+			child.Parameters = make(map[string]interface{}, len(template.Parameters)+len(params))
+			for name, value := range template.Parameters {
+				child.Parameters[name] = value
+			}
+			for name, value := range params {
+				child.Parameters[name] = value
+			}
+			children = append(children, child)
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range children {
+		// Overrides may not move a child out of the group
+		children[i].ID = ""
+		children[i].GroupID = group.ID
+		if children[i].Name == "" || children[i].Name == group.Template.Name {
+			children[i].Name = fmt.Sprintf("%s-%d", baseName, i+1)
+		}
+	}
+	return children, nil
+}
+
+// sweepCombinations returns every combination of the swept parameter
+// values, varying the last parameter (by name) fastest
+func sweepCombinations(sweep map[string][]interface{}) ([]map[string]interface{}, error) {
+	var errs types.ValidationErrors
+	names := make([]string, 0, len(sweep))
+	total := 1
+	for name, values := range sweep {
+		names = append(names, name)
+		if len(values) == 0 {
+			errs.Add("sweep."+name, "must list at least one value")
+			continue
+		}
+		total *= len(values)
+		if total > types.MaxJobGroupSize {
+			errs.Add("sweep", "must not expand to more than %d jobs", types.MaxJobGroupSize)
+			return nil, errs
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	combinations := []map[string]interface{}{{}}
+	for _, name := range names {
+		next := make([]map[string]interface{}, 0, len(combinations)*len(sweep[name]))
+		for _, combination := range combinations {
+			for _, value := range sweep[name] {
+				params := make(map[string]interface{}, len(combination)+1)
+				for k, v := range combination {
+					params[k] = v
+				}
+				params[name] = value
+				next = append(next, params)
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
+
+// PutJobGroup creates or replaces a job group
+func PutJobGroup(ctx context.Context, s3Client *s3.Client, bucket string, group types.JobGroup) error {
+	groupJSON, err := json.Marshal(group)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job group: %v", err)
+	}
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(jobGroupKey(group.ID)),
+		Body:        bytes.NewReader(groupJSON),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put job group in S3: %v", err)
+	}
+	return nil
+}
+
+// GetJobGroup retrieves a job group
+func GetJobGroup(ctx context.Context, s3Client *s3.Client, bucket string, id string) (*types.JobGroup, error) {
+	result, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(jobGroupKey(id)),
+	})
+	if err != nil {
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrJobGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to get job group from S3: %v", err)
+	}
+	defer result.Body.Close()
+
+	var group types.JobGroup
+	if err := json.NewDecoder(result.Body).Decode(&group); err != nil {
+		return nil, fmt.Errorf("failed to decode job group: %v", err)
+	}
+	return &group, nil
+}
+
+// GetJobGroups retrieves every job group, newest first
+func GetJobGroups(ctx context.Context, s3Client *s3.Client, bucket string) ([]types.JobGroup, error) {
+	groups := make([]types.JobGroup, 0)
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String("job-groups/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list job groups: %v", err)
+		}
+		for _, item := range page.Contents {
+			id, ok := strings.CutSuffix(strings.TrimPrefix(*item.Key, "job-groups/"), ".json")
+			if !ok {
+				continue
+			}
+			group, err := GetJobGroup(ctx, s3Client, bucket, id)
+			if err != nil {
+				logrus.Warnf("Failed to get job group %s: %v", id, err)
+				continue
+			}
+			groups = append(groups, *group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].CreatedAt.After(groups[j].CreatedAt)
+	})
+	return groups, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+func TestExpandJobGroupSweep(t *testing.T) {
+	group := types.JobGroup{
+		ID:   "g1",
+		Name: "grid",
+		Template: types.Job{
+			Name:       "rnaseq",
+			Pipeline:   "nf-core/rnaseq",
+			Parameters: map[string]interface{}{"input": "s3://b/sheet.csv"},
+		},
+		Sweep: map[string][]interface{}{
+			"aligner":   {"star_salmon", "hisat2"},
+			"min_reads": {float64(1000), float64(10000), float64(100000)},
+		},
+	}
+	children, err := ExpandJobGroup(group)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(children) != 6 {
+		t.Fatalf("expected 6 children, got %d", len(children))
+	}
+	first, last := children[0], children[5]
+	if first.Name != "grid-1" || last.Name != "grid-6" {
+		t.Errorf("unexpected names %s, %s", first.Name, last.Name)
+	}
+	if first.Parameters["aligner"] != "star_salmon" || first.Parameters["min_reads"] != float64(1000) {
+		t.Errorf("unexpected first combination %v", first.Parameters)
+	}
+	if last.Parameters["aligner"] != "hisat2" || last.Parameters["min_reads"] != float64(100000) {
+		t.Errorf("unexpected last combination %v", last.Parameters)
+	}
+	for _, child := range children {
+		if child.GroupID != "g1" || child.Parameters["input"] != "s3://b/sheet.csv" {
+			t.Errorf("child %s lost its template: %+v", child.Name, child)
+		}
+	}
+	if _, ok := group.Template.Parameters["aligner"]; ok {
+		t.Error("expanding a sweep must not modify the template")
+	}
+}
+
+func TestExpandJobGroupOverrides(t *testing.T) {
+	group := types.JobGroup{
+		ID:       "g2",
+		Template: types.Job{Name: "cohort", Pipeline: "nf-core/rnaseq", Profile: "test"},
+		Overrides: []json.RawMessage{
+			json.RawMessage(`{"parameters": {"input": "s3://b/cohort1.csv"}}`),
+			json.RawMessage(`{"name": "cohort-special", "profile": null, "id": "x", "group_id": "other"}`),
+		},
+	}
+	children, err := ExpandJobGroup(group)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if children[0].Name != "cohort-1" || children[0].Parameters["input"] != "s3://b/cohort1.csv" {
+		t.Errorf("unexpected first child %+v", children[0])
+	}
+	if children[1].Name != "cohort-special" || children[1].Profile != "" || children[1].ID != "" || children[1].GroupID != "g2" {
+		t.Errorf("unexpected second child %+v", children[1])
+	}
+}
+
+func TestExpandJobGroupErrors(t *testing.T) {
+	tests := map[string]types.JobGroup{
+		"neither": {},
+		"both": {
+			Overrides: []json.RawMessage{json.RawMessage(`{}`)},
+			Sweep:     map[string][]interface{}{"a": {1}},
+		},
+		"empty values": {Sweep: map[string][]interface{}{"a": {}}},
+		"too large": {Sweep: map[string][]interface{}{
+			"a": make([]interface{}, 100),
+			"b": make([]interface{}, 100),
+		}},
+		"bad patch": {Overrides: []json.RawMessage{json.RawMessage(`[1]`)}},
+	}
+	for name, group := range tests {
+		if _, err := ExpandJobGroup(group); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+// ApplyJobPatch applies a JSON merge-patch (RFC 7396) to a job spec and
+// returns the patched copy
+func ApplyJobPatch(job types.Job, patch []byte) (*types.Job, error) {
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, fmt.Errorf("failed to decode merge patch: %v", err)
+	}
+	if _, ok := patchDoc.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("merge patch must be a JSON object")
+	}
+
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %v", err)
+	}
+	var jobDoc interface{}
+	if err := json.Unmarshal(jobJSON, &jobDoc); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %v", err)
+	}
+
+	patchedJSON, err := json.Marshal(mergePatch(jobDoc, patchDoc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patched job: %v", err)
+	}
+	var patched types.Job
+	if err := json.Unmarshal(patchedJSON, &patched); err != nil {
+		return nil, fmt.Errorf("failed to decode patched job: %v", err)
+	}
+	return &patched, nil
+}
+
+// mergePatch implements the MergePatch algorithm from RFC 7396
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
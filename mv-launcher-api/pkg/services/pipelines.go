@@ -1,42 +1,236 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/MemVerge/nf-launcher/pkg/types"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/sirupsen/logrus"
 )
 
-func GetPipelines(s3Client *s3.Client, bucket string) (pipelines types.Pipelines, err error) {
+// ErrPipelineNotFound is returned when a pipeline is not in the registry
+var ErrPipelineNotFound = errors.New("pipeline not found")
+
+// ErrSchemaNotFound is returned when a pipeline has no parameter schema
+var ErrSchemaNotFound = errors.New("pipeline schema not found")
+
+// githubRawURL is where pipeline schemas are imported from
+const githubRawURL = "https://raw.githubusercontent.com"
+
+// pipelineKey returns the S3 key of a registered pipeline
+func pipelineKey(name string) string {
+	return name + ".json"
+}
+
+// pipelineSchemaKey returns the S3 key of a pipeline's parameter schema
+func pipelineSchemaKey(name string) string {
+	return name + "/nextflow_schema.json"
+}
+
+func GetPipelines(ctx context.Context, s3Client *s3.Client, bucket string) (pipelines types.Pipelines, err error) {
 	logrus.Infof("Checking pipeline bucket: %s", bucket)
-	result, err := s3Client.ListObjects(context.TODO(), &s3.ListObjectsInput{
+	pipelines = make(types.Pipelines, 0)
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucket),
 	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pipeline objects: %v", err)
+		}
+		for _, item := range page.Contents {
+			// Only top-level JSON documents are registry entries
+			if !strings.HasSuffix(*item.Key, ".json") || strings.Contains(*item.Key, "/") {
+				continue
+			}
+			pipeline, err := getPipelineObject(ctx, s3Client, bucket, *item.Key)
+			if err != nil {
+				logrus.Warnf("Failed to read pipeline object %s: %v", *item.Key, err)
+				continue
+			}
+			pipelines = append(pipelines, *pipeline)
+		}
+	}
+	logrus.Infof("Found %d pipelines", len(pipelines))
+	return pipelines, nil
+}
+
+// GetPipeline retrieves a pipeline from the registry
+func GetPipeline(ctx context.Context, s3Client *s3.Client, bucket string, name string) (*types.Pipeline, error) {
+	pipeline, err := getPipelineObject(ctx, s3Client, bucket, pipelineKey(name))
 	if err != nil {
-		return nil, fmt.Errorf("failed to list pipeline objects: %v", err)
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrPipelineNotFound
+		}
+		return nil, err
 	}
-	logrus.Infof("Found %d pipelines", len(result.Contents))
-	for _, item := range result.Contents {
-		objInput := &s3.GetObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(*item.Key),
+	return pipeline, nil
+}
+
+// FindPipeline looks up the registry entry a job's pipeline refers to, by
+// name or source repo. Names are looked up directly; only source repos need
+// a scan of the registry.
+func FindPipeline(ctx context.Context, s3Client *s3.Client, bucket string, ref string) (*types.Pipeline, error) {
+	if !strings.Contains(ref, "/") {
+		pipeline, err := GetPipeline(ctx, s3Client, bucket, ref)
+		if !errors.Is(err, ErrPipelineNotFound) {
+			return pipeline, err
 		}
-		objResult, err := s3Client.GetObject(context.Background(), objInput)
-		if err != nil {
-			logrus.Warnf("Failed to get pipeline object %s: %v", *item.Key, err)
-			continue
+	}
+
+	pipelines, err := GetPipelines(ctx, s3Client, bucket)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pipelines {
+		if pipelines[i].SourceRepo == ref {
+			return &pipelines[i], nil
 		}
-		defer objResult.Body.Close()
-		var pipeline types.Pipeline
-		if err := json.NewDecoder(objResult.Body).Decode(&pipeline); err != nil {
-			logrus.Warnf("Failed to decode pipeline object %s: %v", *item.Key, err)
-			continue
+	}
+	return nil, ErrPipelineNotFound
+}
+
+func getPipelineObject(ctx context.Context, s3Client *s3.Client, bucket string, key string) (*types.Pipeline, error) {
+	objResult, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer objResult.Body.Close()
+
+	var pipeline types.Pipeline
+	if err := json.NewDecoder(objResult.Body).Decode(&pipeline); err != nil {
+		return nil, fmt.Errorf("failed to decode pipeline: %v", err)
+	}
+	return &pipeline, nil
+}
+
+// PutPipeline creates or replaces a pipeline in the registry
+func PutPipeline(ctx context.Context, s3Client *s3.Client, bucket string, pipeline types.Pipeline) error {
+	pipelineJSON, err := json.Marshal(pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pipeline: %v", err)
+	}
+
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(pipelineKey(pipeline.Name)),
+		Body:        bytes.NewReader(pipelineJSON),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put pipeline in S3: %v", err)
+	}
+	return nil
+}
+
+// DeletePipeline removes a pipeline from the registry
+func DeletePipeline(ctx context.Context, s3Client *s3.Client, bucket string, name string) error {
+	_, err := s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(pipelineKey(name)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete pipeline from S3: %v", err)
+	}
+	return nil
+}
+
+// GetPipelineSchema retrieves the nextflow_schema.json stored for a pipeline
+func GetPipelineSchema(ctx context.Context, s3Client *s3.Client, bucket string, name string) ([]byte, error) {
+	result, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(pipelineSchemaKey(name)),
+	})
+	if err != nil {
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrSchemaNotFound
 		}
-		pipelines = append(pipelines, pipeline)
+		return nil, fmt.Errorf("failed to get pipeline schema from S3: %v", err)
 	}
-	return pipelines, nil
+	defer result.Body.Close()
+
+	schema, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline schema: %v", err)
+	}
+	return schema, nil
+}
+
+// PutPipelineSchema stores the nextflow_schema.json of a pipeline
+func PutPipelineSchema(ctx context.Context, s3Client *s3.Client, bucket string, name string, schema []byte) error {
+	_, err := s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(pipelineSchemaKey(name)),
+		Body:        bytes.NewReader(schema),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put pipeline schema in S3: %v", err)
+	}
+	return nil
+}
+
+// DeletePipelineSchema removes the parameter schema of a pipeline
+func DeletePipelineSchema(ctx context.Context, s3Client *s3.Client, bucket string, name string) error {
+	_, err := s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(pipelineSchemaKey(name)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete pipeline schema from S3: %v", err)
+	}
+	return nil
+}
+
+// FetchPipelineSchema downloads nextflow_schema.json from a pipeline's
+// GitHub repository at revision, or at the default branch if revision is
+// empty
+func FetchPipelineSchema(ctx context.Context, sourceRepo string, revision string) ([]byte, error) {
+	repo := strings.TrimSuffix(strings.TrimPrefix(sourceRepo, "https://github.com/"), ".git")
+	if strings.Contains(repo, "://") || strings.Count(repo, "/") != 1 {
+		return nil, fmt.Errorf("can only import schemas from GitHub repositories, got %s", sourceRepo)
+	}
+	if revision == "" {
+		revision = "HEAD"
+	}
+
+	url := fmt.Sprintf("%s/%s/%s/nextflow_schema.json", githubRawURL, repo, revision)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSchemaNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	schema, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", url, err)
+	}
+	logrus.Infof("Fetched pipeline schema from %s", url)
+	return schema, nil
 }
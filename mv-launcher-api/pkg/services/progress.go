@@ -0,0 +1,62 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrProgressNotFound is returned when a job has not reported any progress
+var ErrProgressNotFound = errors.New("progress not found")
+
+// progressKey returns the S3 key of a job's progress, kept next to its spec
+func progressKey(jobID string) string {
+	return fmt.Sprintf("jobs/%s/progress.json", jobID)
+}
+
+// PutWorkflowProgress stores the weblog progress of a job
+func PutWorkflowProgress(ctx context.Context, s3Client *s3.Client, bucket string, progress types.WorkflowProgress) error {
+	progressJSON, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %v", err)
+	}
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(progressKey(progress.JobID)),
+		Body:        bytes.NewReader(progressJSON),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put progress in S3: %v", err)
+	}
+	return nil
+}
+
+// GetWorkflowProgress retrieves the weblog progress of a job
+func GetWorkflowProgress(ctx context.Context, s3Client *s3.Client, bucket string, jobID string) (*types.WorkflowProgress, error) {
+	result, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(progressKey(jobID)),
+	})
+	if err != nil {
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrProgressNotFound
+		}
+		return nil, fmt.Errorf("failed to get progress from S3: %v", err)
+	}
+	defer result.Body.Close()
+
+	var progress types.WorkflowProgress
+	if err := json.NewDecoder(result.Body).Decode(&progress); err != nil {
+		return nil, fmt.Errorf("failed to decode progress: %v", err)
+	}
+	return &progress, nil
+}
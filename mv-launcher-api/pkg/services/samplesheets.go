@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrSamplesheetNotFound is returned when a samplesheet was never uploaded
+var ErrSamplesheetNotFound = errors.New("samplesheet not found")
+
+// samplesheetKey returns the S3 key of a file kept for a samplesheet
+func samplesheetKey(id string, name string) string {
+	return fmt.Sprintf("samplesheets/%s/%s", id, name)
+}
+
+// SamplesheetFileName is the name an uploaded samplesheet is stored under
+func SamplesheetFileName(format string) string {
+	return "samplesheet." + format
+}
+
+// SamplesheetURI returns where the head node of a job reads a samplesheet
+func SamplesheetURI(bucket string, id string, format string) string {
+	return fmt.Sprintf("s3://%s/%s", bucket, samplesheetKey(id, SamplesheetFileName(format)))
+}
+
+// PutSamplesheet stores an uploaded samplesheet and its metadata as
+// samplesheets/<id>/samplesheet.<format> and samplesheets/<id>/samplesheet.json
+func PutSamplesheet(ctx context.Context, s3Client *s3.Client, bucket string, sheet types.Samplesheet, body []byte) error {
+	_, err := s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(samplesheetKey(sheet.ID, SamplesheetFileName(sheet.Format))),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put samplesheet in S3: %v", err)
+	}
+
+	sheetJSON, err := json.Marshal(sheet)
+	if err != nil {
+		return fmt.Errorf("failed to marshal samplesheet: %v", err)
+	}
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(samplesheetKey(sheet.ID, "samplesheet.json")),
+		Body:        bytes.NewReader(sheetJSON),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put samplesheet metadata in S3: %v", err)
+	}
+	return nil
+}
+
+// GetSamplesheet retrieves the metadata of an uploaded samplesheet
+func GetSamplesheet(ctx context.Context, s3Client *s3.Client, bucket string, id string) (*types.Samplesheet, error) {
+	result, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(samplesheetKey(id, "samplesheet.json")),
+	})
+	if err != nil {
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrSamplesheetNotFound
+		}
+		return nil, fmt.Errorf("failed to get samplesheet from S3: %v", err)
+	}
+	defer result.Body.Close()
+
+	var sheet types.Samplesheet
+	if err := json.NewDecoder(result.Body).Decode(&sheet); err != nil {
+		return nil, fmt.Errorf("failed to decode samplesheet: %v", err)
+	}
+	return &sheet, nil
+}
+
+// MissingObjects checks that S3 objects exist and returns the URIs that
+// cannot be found or read, with the reason
+func MissingObjects(ctx context.Context, s3Client *s3.Client, uris []string) map[string]error {
+	missing := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s3FetchWorkers)
+	for _, uri := range uris {
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(uri, "s3://"), "/")
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(bucket),
+				Key:    aws.String(key),
+			})
+			if err != nil {
+				mu.Lock()
+				missing[uri] = err
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return missing
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrScheduleNotFound is returned when a schedule does not exist
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// scheduleKey returns the S3 key of a schedule
+func scheduleKey(id string) string {
+	return fmt.Sprintf("schedules/%s.json", id)
+}
+
+// PutSchedule creates or replaces a schedule
+func PutSchedule(ctx context.Context, s3Client *s3.Client, bucket string, schedule types.Schedule) error {
+	scheduleJSON, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %v", err)
+	}
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(scheduleKey(schedule.ID)),
+		Body:        bytes.NewReader(scheduleJSON),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put schedule in S3: %v", err)
+	}
+	return nil
+}
+
+// GetSchedule retrieves a schedule
+func GetSchedule(ctx context.Context, s3Client *s3.Client, bucket string, id string) (*types.Schedule, error) {
+	result, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(scheduleKey(id)),
+	})
+	if err != nil {
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, fmt.Errorf("failed to get schedule from S3: %v", err)
+	}
+	defer result.Body.Close()
+
+	var schedule types.Schedule
+	if err := json.NewDecoder(result.Body).Decode(&schedule); err != nil {
+		return nil, fmt.Errorf("failed to decode schedule: %v", err)
+	}
+	return &schedule, nil
+}
+
+// GetSchedules retrieves every schedule, sorted by name
+func GetSchedules(ctx context.Context, s3Client *s3.Client, bucket string) ([]types.Schedule, error) {
+	schedules := make([]types.Schedule, 0)
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String("schedules/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list schedules: %v", err)
+		}
+		for _, item := range page.Contents {
+			id, ok := strings.CutSuffix(strings.TrimPrefix(*item.Key, "schedules/"), ".json")
+			if !ok {
+				continue
+			}
+			schedule, err := GetSchedule(ctx, s3Client, bucket, id)
+			if err != nil {
+				logrus.Warnf("Failed to get schedule %s: %v", id, err)
+				continue
+			}
+			schedules = append(schedules, *schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Name < schedules[j].Name
+	})
+	return schedules, nil
+}
+
+// DeleteSchedule removes a schedule
+func DeleteSchedule(ctx context.Context, s3Client *s3.Client, bucket string, id string) error {
+	_, err := s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(scheduleKey(id)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule from S3: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MemVerge/nf-launcher/pkg/trace"
+	"github.com/MemVerge/nf-launcher/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrTraceNotFound is returned when the head node of a job has not
+// uploaded a trace yet
+var ErrTraceNotFound = errors.New("trace not found")
+
+// GetJobTrace reads the tasks of a job from the trace its head node
+// uploaded next to its logs
+func GetJobTrace(ctx context.Context, s3Client *s3.Client, bucket string, jobID string) ([]types.Task, error) {
+	result, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(fmt.Sprintf("jobs/%s/%s", jobID, trace.FileName)),
+	})
+	if err != nil {
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrTraceNotFound
+		}
+		return nil, fmt.Errorf("failed to get trace from S3: %v", err)
+	}
+	defer result.Body.Close()
+
+	tasks, err := trace.Parse(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace: %v", err)
+	}
+	return tasks, nil
+}
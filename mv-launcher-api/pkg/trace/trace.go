@@ -0,0 +1,215 @@
+package trace
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MemVerge/nf-launcher/pkg/types"
+)
+
+// FileName is the name the head node writes its trace to and uploads it
+// under, next to nextflow.log
+const FileName = "trace.txt"
+
+// Fields are the trace columns head nodes are configured to write. Parse
+// reads columns by name, so traces with other fields still parse.
+var Fields = []string{
+	"task_id", "hash", "native_id", "process", "tag", "name", "status", "exit", "attempt",
+	"submit", "start", "complete", "duration", "realtime",
+	"cpus", "memory", "%cpu", "%mem", "peak_rss", "peak_vmem", "rchar", "wchar", "workdir",
+}
+
+// timestampLayout is how Nextflow formats dates in the trace
+const timestampLayout = "2006-01-02 15:04:05.000"
+
+// Parse reads a Nextflow trace file. Missing values ("-") are left zero.
+func Parse(r io.Reader) ([]types.Task, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("trace is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]types.Task, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The head node may upload the trace while a line is half written
+		if len(record) != len(header) {
+			continue
+		}
+
+		var task types.Task
+		for i, field := range header {
+			if err := setField(&task, field, strings.TrimSpace(record[i])); err != nil {
+				return nil, fmt.Errorf("line %d: %s: %v", line, field, err)
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// setField parses a single trace value into a task
+func setField(task *types.Task, field, value string) error {
+	if value == "-" || value == "" {
+		return nil
+	}
+
+	var err error
+	switch field {
+	case "task_id":
+		task.TaskID, err = strconv.Atoi(value)
+	case "hash":
+		task.Hash = value
+	case "native_id":
+		task.NativeID = value
+	case "process":
+		task.Process = value
+	case "tag":
+		task.Tag = value
+	case "name":
+		task.Name = value
+	case "status":
+		task.Status = value
+	case "exit":
+		var exit int
+		exit, err = strconv.Atoi(value)
+		task.Exit = &exit
+	case "attempt":
+		task.Attempt, err = strconv.Atoi(value)
+	case "submit":
+		task.SubmittedAt, err = ParseTimestamp(value)
+	case "start":
+		task.StartedAt, err = ParseTimestamp(value)
+	case "complete":
+		task.CompletedAt, err = ParseTimestamp(value)
+	case "duration":
+		var d time.Duration
+		d, err = ParseDuration(value)
+		task.Duration = d.Milliseconds()
+	case "realtime":
+		var d time.Duration
+		d, err = ParseDuration(value)
+		task.Realtime = d.Milliseconds()
+	case "cpus":
+		task.CPUs, err = strconv.Atoi(value)
+	case "memory":
+		task.Memory, err = ParseMemory(value)
+	case "%cpu":
+		task.CPUPercent, err = ParsePercent(value)
+	case "%mem":
+		task.MemPercent, err = ParsePercent(value)
+	case "peak_rss":
+		task.PeakRSS, err = ParseMemory(value)
+	case "peak_vmem":
+		task.PeakVMem, err = ParseMemory(value)
+	case "rchar":
+		task.ReadBytes, err = ParseMemory(value)
+	case "wchar":
+		task.WriteBytes, err = ParseMemory(value)
+	case "workdir":
+		task.WorkDir = value
+	}
+	return err
+}
+
+// ParseTimestamp parses a trace date, which Nextflow writes in the head
+// node's time zone, UTC in its container. Raw traces use epoch
+// milliseconds.
+func ParseTimestamp(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	return time.Parse(timestampLayout, s)
+}
+
+// durationUnits are the units of Nextflow's duration format
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+}
+
+// ParseDuration parses a Nextflow duration such as "1h 2m 3s" or "1.5s".
+// A plain number is taken as milliseconds, as in raw traces.
+func ParseDuration(s string) (time.Duration, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+
+	parts := strings.Fields(s)
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var total time.Duration
+	for _, part := range parts {
+		number := strings.TrimRight(part, "abcdefghijklmnopqrstuvwxyz")
+		unit, ok := durationUnits[part[len(number):]]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += time.Duration(value * float64(unit))
+	}
+	return total, nil
+}
+
+// memoryUnits are the units of Nextflow's memory format, in powers of 1024
+var memoryUnits = map[string]float64{
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
+	"PB": 1 << 50,
+}
+
+// ParseMemory parses a Nextflow memory size such as "1.5 GB" into bytes. A
+// plain number is taken as bytes, as in raw traces.
+func ParseMemory(s string) (int64, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+
+	number, unit, ok := strings.Cut(s, " ")
+	multiplier, known := memoryUnits[strings.ToUpper(unit)]
+	if !ok || !known {
+		return 0, fmt.Errorf("invalid memory size %q", s)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory size %q", s)
+	}
+	return int64(value * multiplier), nil
+}
+
+// ParsePercent parses a percentage such as "98.5%"
+func ParsePercent(s string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return value, nil
+}
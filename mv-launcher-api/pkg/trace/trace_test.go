@@ -0,0 +1,102 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"120ms", 120 * time.Millisecond},
+		{"1.5s", 1500 * time.Millisecond},
+		{"3m 2s", 3*time.Minute + 2*time.Second},
+		{"1d 2h 3m 4s", 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{"4500", 4500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "3 weeks", "fast"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q) expected an error", in)
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"10 B", 10},
+		{"512 KB", 512 << 10},
+		{"1.5 GB", 3 << 29},
+		{"2 TB", 2 << 40},
+		{"1048576", 1 << 20},
+	}
+	for _, tt := range tests {
+		got, err := ParseMemory(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMemory(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"1.5GB", "3 GiB", "lots MB"} {
+		if _, err := ParseMemory(in); err == nil {
+			t.Errorf("ParseMemory(%q) expected an error", in)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	if got, err := ParsePercent("98.5%"); err != nil || got != 98.5 {
+		t.Errorf("ParsePercent(98.5%%) = %v, %v", got, err)
+	}
+	if _, err := ParsePercent("high"); err == nil {
+		t.Error("expected an error for a non-numeric percentage")
+	}
+}
+
+func TestParse(t *testing.T) {
+	trace := strings.Join([]string{
+		"task_id\thash\tnative_id\tprocess\tname\tstatus\texit\tsubmit\tduration\trealtime\t%cpu\tpeak_rss",
+		"1\tab/123456\tbatch-1\tFASTQC\tFASTQC (a)\tCOMPLETED\t0\t2025-03-01 10:00:00.123\t3m 2s\t2m 50s\t98.5%\t1.5 GB",
+		"2\tcd/654321\t-\tMULTIQC\tMULTIQC\tFAILED\t1\t2025-03-01 10:04:00.000\t10s\t-\t-\t-",
+		"3\tef/000000\tbatch-3",
+	}, "\n") + "\n"
+
+	tasks, err := Parse(strings.NewReader(trace))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// The half-written last line is skipped
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %+v", tasks)
+	}
+
+	fastqc := tasks[0]
+	if fastqc.TaskID != 1 || fastqc.Process != "FASTQC" || fastqc.NativeID != "batch-1" || fastqc.Exit == nil || *fastqc.Exit != 0 {
+		t.Errorf("unexpected task %+v", fastqc)
+	}
+	if fastqc.Duration != 182000 || fastqc.Realtime != 170000 || fastqc.CPUPercent != 98.5 || fastqc.PeakRSS != 3<<29 {
+		t.Errorf("unexpected usage %+v", fastqc)
+	}
+	if want := time.Date(2025, 3, 1, 10, 0, 0, 123e6, time.UTC); !fastqc.SubmittedAt.Equal(want) {
+		t.Errorf("submitted at %s, want %s", fastqc.SubmittedAt, want)
+	}
+
+	multiqc := tasks[1]
+	if multiqc.Status != "FAILED" || multiqc.NativeID != "" || multiqc.Realtime != 0 {
+		t.Errorf("unexpected task %+v", multiqc)
+	}
+
+	if _, err := Parse(strings.NewReader("task_id\tduration\n1\tsoon\n")); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
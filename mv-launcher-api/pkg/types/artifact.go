@@ -0,0 +1,13 @@
+package types
+
+import "time"
+
+// Artifact is a file a job's head node uploaded next to its logs
+type Artifact struct {
+	Name         string    `json:"name" example:"report.html"`
+	Key          string    `json:"key" example:"jobs/1234/report.html"`
+	Size         int64     `json:"size" example:"482133"`
+	Type         string    `json:"type" example:"text/html; charset=utf-8"`
+	LastModified time.Time `json:"last_modified"`
+	URL          string    `json:"url,omitempty"`
+}
@@ -1,25 +1,230 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxRetriesLimit is the highest number of task retries a job may ask for
+const MaxRetriesLimit = 10
+
+// JobStatusQueued is the status of a job the launcher holds back before
+// submitting it to AWS Batch, such as a job group child waiting for a slot
+const JobStatusQueued = "QUEUED"
+
+// MaxSubmitAttempts is how often a queued job is submitted to AWS Batch
+// before it is failed
+const MaxSubmitAttempts = 5
+
+// memoryPattern matches Nextflow memory units such as 20G, 512 MB or 1.5.GB
+var memoryPattern = regexp.MustCompile(`(?i)^\d+(\.\d+)?\s*\.?\s*[KMGTP]?B?$`)
 
 type Job struct {
-	ID            string            `json:"id"`
-	Name          string            `json:"name"`
-	Pipeline      string            `json:"pipeline"`
-	Parameters    map[string]string `json:"parameters"`
-	Status        string            `json:"status"`
-	CreatedAt     time.Time         `json:"created_at"`
-	UpdatedAt     time.Time         `json:"updated_at"`
-	BatchJobId    string            `json:"batch_job_id"`
-	Memory        string            `json:"memory"`
-	MaxRetries    int               `json:"max_retries"`
-	HeadNodeQueue string            `json:"head_node_queue"`
-	TaskQueue     string            `json:"task_queue"`
-	WorkDir       string            `json:"work_dir"`
-	ResultDir     string            `json:"result_dir"`
-	LogBucket     string            `json:"log_bucket"`
-	AWSAccessKey  string            `json:"aws_access_key"`
-	AWSSecretKey  string            `json:"aws_secret_key"`
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	User             string                 `json:"user,omitempty"`
+	Pipeline         string                 `json:"pipeline"`
+	SourceRepo       string                 `json:"source_repo,omitempty"`
+	Profile          string                 `json:"profile,omitempty"`
+	Revision         string                 `json:"revision,omitempty"`
+	CommitID         string                 `json:"commit_id,omitempty"`
+	SamplesheetID    string                 `json:"samplesheet_id,omitempty"`
+	GroupID          string                 `json:"group_id,omitempty"`
+	DependsOn        []string               `json:"depends_on,omitempty"`
+	ScheduleID       string                 `json:"schedule_id,omitempty"`
+	Parameters       map[string]interface{} `json:"parameters" swaggertype:"object" example:"input:s3://bucket/samplesheet.csv,skip_trimming:true"`
+	Status           string                 `json:"status"`
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+	BatchJobId       string                 `json:"batch_job_id"`
+	BatchJobArn      string                 `json:"batch_job_arn,omitempty"`
+	StatusHistory    []StatusTransition     `json:"status_history,omitempty"`
+	Batch            *BatchState            `json:"batch,omitempty"`
+	Memory           string                 `json:"memory"`
+	MaxRetries       int                    `json:"max_retries"`
+	HeadNodeQueue    string                 `json:"head_node_queue"`
+	TaskQueue        string                 `json:"task_queue"`
+	WorkDir          string                 `json:"work_dir"`
+	ResultDir        string                 `json:"result_dir"`
+	LogBucket        string                 `json:"log_bucket"`
+	AdditionalConfig string                 `json:"additional_config,omitempty"`
+	AWSAccessKey     string                 `json:"aws_access_key"`
+	AWSSecretKey     string                 `json:"aws_secret_key"`
+	Cancellation     *Cancellation          `json:"cancellation,omitempty"`
+	ParentID         string                 `json:"parent_id,omitempty"`
+	Attempt          int                    `json:"attempt,omitempty"`
+	Resume           bool                   `json:"resume,omitempty"`
+	SessionID        string                 `json:"session_id,omitempty"`
+	SubmitAttempts   int                    `json:"submit_attempts,omitempty"`
+	SubmitError      string                 `json:"submit_error,omitempty"`
 }
 
 type Jobs []Job
+
+// Verify normalises the job spec and checks it for errors that do not need
+// AWS to detect. Bucket names given for the work and result directories
+// become s3:// URIs and the log bucket becomes a plain bucket name.
+func (j *Job) Verify() error {
+	j.WorkDir = toS3URI(j.WorkDir)
+	j.ResultDir = toS3URI(j.ResultDir)
+	j.LogBucket = strings.Trim(strings.TrimPrefix(strings.TrimSpace(j.LogBucket), "s3://"), "/")
+
+	var errs ValidationErrors
+	required := []struct {
+		field string
+		value string
+	}{
+		{"pipeline", j.Pipeline},
+		{"work_dir", j.WorkDir},
+		{"result_dir", j.ResultDir},
+		{"head_node_queue", j.HeadNodeQueue},
+		{"task_queue", j.TaskQueue},
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			errs.Add(r.field, "is required")
+		}
+	}
+	if j.Memory != "" && !memoryPattern.MatchString(j.Memory) {
+		errs.Add("memory", "must be a memory size such as 20G or 512 MB, got %q", j.Memory)
+	}
+	if j.MaxRetries < 0 || j.MaxRetries > MaxRetriesLimit {
+		errs.Add("max_retries", "must be between 0 and %d", MaxRetriesLimit)
+	}
+	for name := range j.Parameters {
+		if strings.TrimSpace(name) == "" || strings.HasPrefix(name, "-") {
+			errs.Add("parameters."+name, "must be a parameter name without leading dashes")
+		}
+	}
+	return errs.Err()
+}
+
+// toS3URI turns a bucket name or bucket/path into an s3:// URI
+func toS3URI(location string) string {
+	location = strings.TrimSpace(location)
+	if location == "" || strings.Contains(location, "://") {
+		return location
+	}
+	return "s3://" + strings.TrimPrefix(location, "/")
+}
+
+// S3Bucket returns the bucket of an s3:// URI, or "" for other locations
+func S3Bucket(uri string) string {
+	rest, ok := strings.CutPrefix(uri, "s3://")
+	if !ok {
+		return ""
+	}
+	bucket, _, _ := strings.Cut(rest, "/")
+	return bucket
+}
+
+// StatusTransition is a change of a job's status
+type StatusTransition struct {
+	Status string    `json:"status" example:"RUNNING"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// BatchState is the last observed AWS Batch state of a job's head node
+type BatchState struct {
+	JobQueue        string    `json:"job_queue,omitempty"`
+	JobDefinition   string    `json:"job_definition,omitempty"`
+	CreatedAt       time.Time `json:"created_at,omitempty"`
+	StartedAt       time.Time `json:"started_at,omitempty"`
+	StoppedAt       time.Time `json:"stopped_at,omitempty"`
+	ExitCode        int32     `json:"exit_code,omitempty"`
+	StatusReason    string    `json:"status_reason,omitempty"`
+	ContainerReason string    `json:"container_reason,omitempty"`
+	LogStreamName   string    `json:"log_stream_name,omitempty"`
+	Attempts        int32     `json:"attempts,omitempty"`
+	Memory          int32     `json:"memory,omitempty"` // Memory in MB
+	Vcpus           int32     `json:"vcpus,omitempty"`
+}
+
+// IsTerminal reports whether the job has finished running
+func (j Job) IsTerminal() bool {
+	return j.Status == "SUCCEEDED" || j.Status == "FAILED"
+}
+
+// Repo returns the pipeline Nextflow runs for the job: the source repo of
+// its registered pipeline, or the pipeline as given
+func (j Job) Repo() string {
+	if j.SourceRepo != "" {
+		return j.SourceRepo
+	}
+	return j.Pipeline
+}
+
+// Redacted returns a copy of the job without its AWS credentials
+func (j Job) Redacted() Job {
+	j.AWSAccessKey = ""
+	j.AWSSecretKey = ""
+	return j
+}
+
+// RecordStatus sets the status of the job and appends the change to its
+// history. Repeating the current status is a no-op.
+func (j *Job) RecordStatus(status, reason string, at time.Time) {
+	if status == j.Status {
+		return
+	}
+	j.Status = status
+	j.StatusHistory = append(j.StatusHistory, StatusTransition{
+		Status: status,
+		At:     at,
+		Reason: reason,
+	})
+	if at.After(j.UpdatedAt) {
+		j.UpdatedAt = at
+	}
+}
+
+// RecordSubmitFailure counts a failed attempt to submit a queued job and
+// fails the job once MaxSubmitAttempts have failed. It reports whether the
+// job was failed.
+func (j *Job) RecordSubmitFailure(err error, at time.Time) bool {
+	j.SubmitAttempts++
+	j.SubmitError = err.Error()
+	j.UpdatedAt = at
+	if j.SubmitAttempts < MaxSubmitAttempts {
+		return false
+	}
+	j.RecordStatus("FAILED", fmt.Sprintf("Submission failed %d times: %v", j.SubmitAttempts, err), at)
+	return true
+}
+
+// Derive returns a copy of the job spec with its run state cleared, linked
+// to j as its parent and ready to be submitted as a new job.
+func (j Job) Derive() Job {
+	child := j
+	child.ID = ""
+	child.ParentID = j.ID
+	child.Status = ""
+	child.BatchJobId = ""
+	child.BatchJobArn = ""
+	child.StatusHistory = nil
+	child.Batch = nil
+	child.Cancellation = nil
+	child.Attempt = 0
+	child.Resume = false
+	child.SessionID = ""
+	child.SubmitAttempts = 0
+	child.SubmitError = ""
+	child.CommitID = ""
+	child.GroupID = ""
+	child.ScheduleID = ""
+	child.CreatedAt = time.Now().UTC()
+	child.UpdatedAt = child.CreatedAt
+	return child
+}
+
+// Cancellation records who stopped a job, why, and how.
+type Cancellation struct {
+	By         string    `json:"by,omitempty" example:"jdoe"`
+	Reason     string    `json:"reason" example:"wrong reference genome"`
+	Action     string    `json:"action" example:"TerminateJob"`
+	At         time.Time `json:"at"`
+	SweptTasks []string  `json:"swept_tasks,omitempty"`
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// DefaultJobGroupConcurrency is how many children of a job group run
+	// at once unless the group says otherwise
+	DefaultJobGroupConcurrency = 10
+	// MaxJobGroupSize is the largest number of jobs a group may fan out to
+	MaxJobGroupSize = 1000
+)
+
+// JobGroup is a set of jobs launched from one template, either with a list
+// of overrides or as a cartesian sweep over parameter values
+type JobGroup struct {
+	ID       string `json:"id"`
+	Name     string `json:"name" example:"rnaseq-cohorts"`
+	User     string `json:"user,omitempty"`
+	ParentID string `json:"parent_id,omitempty"`
+	Template Job    `json:"template"`
+	// Overrides are JSON merge patches, one child job each
+	Overrides []json.RawMessage `json:"overrides,omitempty" swaggertype:"array,object"`
+	// Sweep maps parameter names to the values to launch every
+	// combination of
+	Sweep       map[string][]interface{} `json:"sweep,omitempty" swaggertype:"object"`
+	Concurrency int                      `json:"concurrency,omitempty" example:"10"`
+	JobIDs      []string                 `json:"job_ids"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at"`
+}
+
+// Redacted returns a copy of the group without the AWS credentials of its
+// template
+func (g JobGroup) Redacted() JobGroup {
+	g.Template = g.Template.Redacted()
+	return g
+}
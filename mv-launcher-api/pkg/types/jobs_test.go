@@ -1,6 +1,11 @@
 package types
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
 
 func TestJobVerify(t *testing.T) {
 	j := Job{
@@ -15,3 +20,107 @@ func TestJobVerify(t *testing.T) {
 		t.Errorf("ResultDir should be prefixed with s3://, got %s", j.ResultDir)
 	}
 }
+
+func TestJobVerifyErrors(t *testing.T) {
+	j := Job{
+		Pipeline:      "nf-core/rnaseq",
+		WorkDir:       "s3://work",
+		Memory:        "lots",
+		MaxRetries:    MaxRetriesLimit + 1,
+		HeadNodeQueue: "head",
+	}
+	err := j.Verify()
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Verify should return ValidationErrors, got %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, fieldErr := range errs {
+		fields[fieldErr.Field] = true
+	}
+	for _, field := range []string{"result_dir", "task_queue", "memory", "max_retries"} {
+		if !fields[field] {
+			t.Errorf("expected an error for %s, got %v", field, errs)
+		}
+	}
+	if fields["work_dir"] || fields["pipeline"] {
+		t.Errorf("unexpected errors for valid fields: %v", errs)
+	}
+}
+
+func TestJobVerifyMemory(t *testing.T) {
+	for _, memory := range []string{"20G", "20 GB", "512MB", "1.5 GB", "8.GB", "4g"} {
+		j := Job{Memory: memory}
+		var errs ValidationErrors
+		errors.As(j.Verify(), &errs)
+		for _, fieldErr := range errs {
+			if fieldErr.Field == "memory" {
+				t.Errorf("memory %q should be valid: %s", memory, fieldErr.Message)
+			}
+		}
+	}
+}
+
+func TestS3Bucket(t *testing.T) {
+	tests := map[string]string{
+		"s3://bucket/some/path": "bucket",
+		"s3://bucket":           "bucket",
+		"/local/path":           "",
+	}
+	for uri, want := range tests {
+		if got := S3Bucket(uri); got != want {
+			t.Errorf("S3Bucket(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
+
+func TestJobParameters(t *testing.T) {
+	var j Job
+	err := json.Unmarshal([]byte(`{"parameters": {"input": "s3://bucket/samplesheet.csv", "min_reads": 100, "skip_qc": true, "genomes": {"GRCh38": {"fasta": "s3://refs/GRCh38.fa"}}}}`), &j)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if j.Parameters["min_reads"] != float64(100) || j.Parameters["skip_qc"] != true {
+		t.Errorf("expected typed parameters, got %v", j.Parameters)
+	}
+	if _, ok := j.Parameters["genomes"].(map[string]interface{}); !ok {
+		t.Errorf("expected nested parameters to decode as an object, got %T", j.Parameters["genomes"])
+	}
+
+	j.Parameters["--input"] = "s3://bucket/other.csv"
+	var errs ValidationErrors
+	if !errors.As(j.Verify(), &errs) {
+		t.Fatal("expected validation errors")
+	}
+	found := false
+	for _, e := range errs {
+		if e.Field == "parameters.--input" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an error for a dashed parameter name, got %v", errs)
+	}
+}
+
+func TestJobRecordSubmitFailure(t *testing.T) {
+	j := Job{Status: JobStatusQueued}
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 1; i < MaxSubmitAttempts; i++ {
+		if j.RecordSubmitFailure(errors.New("throttled"), at) {
+			t.Fatalf("expected attempt %d to leave the job queued", i)
+		}
+	}
+	if j.Status != JobStatusQueued || j.SubmitAttempts != MaxSubmitAttempts-1 || j.SubmitError != "throttled" {
+		t.Fatalf("unexpected job %+v", j)
+	}
+
+	if !j.RecordSubmitFailure(errors.New("no such queue"), at) {
+		t.Fatal("expected the last attempt to fail the job")
+	}
+	last := j.StatusHistory[len(j.StatusHistory)-1]
+	if j.Status != "FAILED" || last.Reason != "Submission failed 5 times: no such queue" {
+		t.Errorf("unexpected status %s, %+v", j.Status, last)
+	}
+}
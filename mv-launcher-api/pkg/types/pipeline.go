@@ -1,12 +1,109 @@
 package types
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pipelineNamePattern keeps pipeline names usable as S3 keys and URL path
+// segments
+var pipelineNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
 type Pipeline struct {
-	Name       string            `json:"name" example:"nextflow-pipeline"`
-	Image      string            `json:"image" example:"registry.gitlab.com/qnib-pub-containers/qnib/nextflow-workflow-run:24.10.4-1"`
-	Command    string            `json:"command" example:"start.sh Ref::pipeline Ref::work-dir Ref::result-dir"`
-	Parameters map[string]string `json:"parameters" example:"{'pipeline': 'hello', 'work-dir': 'addme', 'result-dir': 'addme'}"`
-	Memory     string            `json:"memory" example:"2048"`
-	VCPUs      string            `json:"vcpus" example:"1"`
+	Name            string            `json:"name" example:"nextflow-pipeline"`
+	Description     string            `json:"description,omitempty" example:"RNA sequencing analysis pipeline"`
+	SourceRepo      string            `json:"source_repo" example:"nf-core/rnaseq"`
+	DefaultProfile  string            `json:"default_profile,omitempty" example:"test"`
+	DefaultRevision string            `json:"default_revision,omitempty" example:"3.14.0"`
+	Revisions       []string          `json:"revisions,omitempty" example:"3.13.2,3.14.0"`
+	Samplesheet     *SamplesheetSpec  `json:"samplesheet,omitempty"`
+	Image           string            `json:"image" example:"registry.gitlab.com/qnib-pub-containers/qnib/nextflow-workflow-run:24.10.4-1"`
+	Command         string            `json:"command" example:"start.sh Ref::pipeline Ref::work-dir Ref::result-dir"`
+	Parameters      map[string]string `json:"parameters" example:"{'pipeline': 'hello', 'work-dir': 'addme', 'result-dir': 'addme'}"`
+	Memory          string            `json:"memory" example:"2048"`
+	VCPUs           string            `json:"vcpus" example:"1"`
+	CreatedAt       time.Time         `json:"created_at,omitempty"`
+	UpdatedAt       time.Time         `json:"updated_at,omitempty"`
 }
 
 type Pipelines []Pipeline
+
+// Verify checks a pipeline registry entry for errors
+func (p *Pipeline) Verify() error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.SourceRepo = strings.TrimSpace(p.SourceRepo)
+
+	var errs ValidationErrors
+	if p.Name == "" {
+		errs.Add("name", "is required")
+	} else if !pipelineNamePattern.MatchString(p.Name) {
+		errs.Add("name", "must start with a letter or digit and contain only letters, digits, '.', '_' and '-'")
+	}
+	if p.SourceRepo == "" {
+		errs.Add("source_repo", "is required")
+	}
+	if p.DefaultRevision != "" && len(p.Revisions) > 0 && !p.AllowsRevision(p.DefaultRevision) {
+		errs.Add("default_revision", "must be one of the listed revisions")
+	}
+	if p.Samplesheet != nil {
+		seen := make(map[string]bool)
+		for i, col := range p.Samplesheet.Columns {
+			field := fmt.Sprintf("samplesheet.columns[%d]", i)
+			if col.Name == "" {
+				errs.Add(field+".name", "is required")
+			} else if seen[col.Name] {
+				errs.Add(field+".name", "duplicates column %s", col.Name)
+			}
+			seen[col.Name] = true
+			if col.Pattern != "" {
+				if _, err := regexp.Compile(col.Pattern); err != nil {
+					errs.Add(field+".pattern", "is not a valid regular expression: %v", err)
+				}
+			}
+		}
+	}
+	if p.Memory != "" {
+		if n, err := strconv.Atoi(p.Memory); err != nil || n <= 0 {
+			errs.Add("memory", "must be a positive number of MiB")
+		}
+	}
+	if p.VCPUs != "" {
+		if n, err := strconv.Atoi(p.VCPUs); err != nil || n <= 0 {
+			errs.Add("vcpus", "must be a positive number")
+		}
+	}
+	return errs.Err()
+}
+
+// AllowsRevision reports whether jobs may run the pipeline at revision. An
+// empty revision list allows any revision.
+func (p Pipeline) AllowsRevision(revision string) bool {
+	if len(p.Revisions) == 0 {
+		return true
+	}
+	for _, r := range p.Revisions {
+		if r == revision {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyTo records the source repo Nextflow runs for a job that refers to
+// the pipeline, fills in the pipeline's defaults and checks the job runs one
+// of its allowed revisions. The job keeps the pipeline as it referred to it.
+func (p Pipeline) ApplyTo(job *Job, errs *ValidationErrors) {
+	job.SourceRepo = p.SourceRepo
+	if job.Profile == "" {
+		job.Profile = p.DefaultProfile
+	}
+	if job.Revision == "" {
+		job.Revision = p.DefaultRevision
+	}
+	if job.Revision != "" && !p.AllowsRevision(job.Revision) {
+		errs.Add("revision", "must be one of %s for pipeline %s", strings.Join(p.Revisions, ", "), p.Name)
+	}
+}
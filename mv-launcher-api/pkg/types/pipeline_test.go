@@ -0,0 +1,75 @@
+package types
+
+import "testing"
+
+func TestPipelineVerify(t *testing.T) {
+	p := Pipeline{Name: " rnaseq ", SourceRepo: "nf-core/rnaseq", Memory: "2048"}
+	if err := p.Verify(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "rnaseq" {
+		t.Errorf("expected trimmed name, got %q", p.Name)
+	}
+
+	p = Pipeline{Name: "bad/name", VCPUs: "zero"}
+	err := p.Verify()
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	fields := map[string]bool{}
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, f := range []string{"name", "source_repo", "vcpus"} {
+		if !fields[f] {
+			t.Errorf("expected error for %s, got %v", f, errs)
+		}
+	}
+}
+
+func TestPipelineRevisions(t *testing.T) {
+	p := Pipeline{Name: "rnaseq", SourceRepo: "nf-core/rnaseq"}
+	if !p.AllowsRevision("dev") {
+		t.Error("a pipeline without revisions should allow any revision")
+	}
+
+	p.Revisions = []string{"3.13.2", "3.14.0"}
+	if !p.AllowsRevision("3.14.0") || p.AllowsRevision("dev") {
+		t.Errorf("unexpected revision check for %v", p.Revisions)
+	}
+
+	p.DefaultRevision = "dev"
+	if err := p.Verify(); err == nil {
+		t.Error("expected an error for a default revision that is not allowed")
+	}
+}
+
+func TestPipelineApplyTo(t *testing.T) {
+	p := Pipeline{
+		Name:            "rnaseq",
+		SourceRepo:      "nf-core/rnaseq",
+		DefaultProfile:  "docker",
+		DefaultRevision: "3.14.0",
+		Revisions:       []string{"3.13.2", "3.14.0"},
+	}
+
+	// Launched by registry name, the job keeps the name but runs the
+	// source repo
+	job := Job{Pipeline: "rnaseq"}
+	var errs ValidationErrors
+	p.ApplyTo(&job, &errs)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if job.Pipeline != "rnaseq" || job.Repo() != "nf-core/rnaseq" || job.Profile != "docker" || job.Revision != "3.14.0" {
+		t.Errorf("unexpected job after applying pipeline: %+v", job)
+	}
+
+	job = Job{Pipeline: "nf-core/rnaseq", Profile: "test", Revision: "dev"}
+	errs = nil
+	p.ApplyTo(&job, &errs)
+	if job.Profile != "test" || len(errs) != 1 || errs[0].Field != "revision" {
+		t.Errorf("expected the job's profile kept and its revision rejected, got %+v, %v", job, errs)
+	}
+}
@@ -0,0 +1,326 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Events Nextflow posts to the URL given with -with-weblog
+const (
+	WeblogStarted          = "started"
+	WeblogProcessSubmitted = "process_submitted"
+	WeblogProcessStarted   = "process_started"
+	WeblogProcessCompleted = "process_completed"
+	WeblogError            = "error"
+	WeblogCompleted        = "completed"
+)
+
+// Workflow statuses reported through the weblog
+const (
+	WorkflowRunning   = "RUNNING"
+	WorkflowSucceeded = "SUCCEEDED"
+	WorkflowFailed    = "FAILED"
+)
+
+// Nextflow task statuses
+const (
+	TaskNew       = "NEW"
+	TaskSubmitted = "SUBMITTED"
+	TaskRunning   = "RUNNING"
+	TaskCompleted = "COMPLETED"
+	TaskFailed    = "FAILED"
+	TaskAborted   = "ABORTED"
+	TaskCached    = "CACHED"
+)
+
+// WeblogEvent is a message Nextflow posts with -with-weblog. Process events
+// and errors carry the trace record of a task; the workflow events carry
+// its metadata.
+type WeblogEvent struct {
+	RunName  string          `json:"runName"`
+	RunID    string          `json:"runId"`
+	Event    string          `json:"event"`
+	UTCTime  time.Time       `json:"utcTime"`
+	Trace    *WeblogTrace    `json:"trace,omitempty"`
+	Metadata *WeblogMetadata `json:"metadata,omitempty"`
+}
+
+// WeblogTrace is the part of a Nextflow trace record the launcher keeps.
+// Times are in epoch milliseconds and durations in milliseconds.
+type WeblogTrace struct {
+	TaskID   int          `json:"task_id"`
+	Hash     string       `json:"hash"`
+	NativeID flexibleText `json:"native_id"`
+	Process  string       `json:"process"`
+	Tag      string       `json:"tag"`
+	Name     string       `json:"name"`
+	Status   string       `json:"status"`
+	Exit     *int         `json:"exit"`
+	Attempt  int          `json:"attempt"`
+	Workdir  string       `json:"workdir"`
+	Submit   int64        `json:"submit"`
+	Start    int64        `json:"start"`
+	Complete int64        `json:"complete"`
+	Duration int64        `json:"duration"`
+	Realtime int64        `json:"realtime"`
+}
+
+// WeblogMetadata is the workflow metadata sent with the started and
+// completed events
+type WeblogMetadata struct {
+	Workflow struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage"`
+	} `json:"workflow"`
+}
+
+// flexibleText decodes a JSON string or number as text. Nextflow reports
+// native IDs as strings for AWS Batch but as numbers for local processes.
+type flexibleText string
+
+func (t *flexibleText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = flexibleText(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*t = flexibleText(n.String())
+	return nil
+}
+
+// Task is a single Nextflow task of a job's run
+type Task struct {
+	TaskID      int       `json:"task_id" example:"12"`
+	Hash        string    `json:"hash,omitempty" example:"3f/7a91c2"`
+	Name        string    `json:"name" example:"NFCORE_RNASEQ:FASTQC (sample1)"`
+	Process     string    `json:"process" example:"NFCORE_RNASEQ:FASTQC"`
+	Tag         string    `json:"tag,omitempty" example:"sample1"`
+	Status      string    `json:"status" example:"COMPLETED"`
+	Attempt     int       `json:"attempt,omitempty"`
+	Exit        *int      `json:"exit,omitempty"`
+	NativeID    string    `json:"native_id,omitempty"`
+	WorkDir     string    `json:"work_dir,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at,omitempty"`
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	Duration    int64     `json:"duration,omitempty"` // Wall time in milliseconds
+	Realtime    int64     `json:"realtime,omitempty"` // Run time in milliseconds
+
+	// Resources requested and used, known once the trace is read
+	CPUs       int     `json:"cpus,omitempty"`
+	Memory     int64   `json:"memory,omitempty"` // Requested memory in bytes
+	CPUPercent float64 `json:"cpu_percent,omitempty"`
+	MemPercent float64 `json:"mem_percent,omitempty"`
+	PeakRSS    int64   `json:"peak_rss,omitempty"`  // Bytes
+	PeakVMem   int64   `json:"peak_vmem,omitempty"` // Bytes
+	ReadBytes  int64   `json:"read_bytes,omitempty"`
+	WriteBytes int64   `json:"write_bytes,omitempty"`
+}
+
+// WorkflowProgress is the state of a job's Nextflow run as reported
+// through the weblog
+type WorkflowProgress struct {
+	JobID        string    `json:"job_id"`
+	RunName      string    `json:"run_name,omitempty"`
+	RunID        string    `json:"run_id,omitempty"`
+	Status       string    `json:"status" example:"RUNNING"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	StartedAt    time.Time `json:"started_at,omitempty"`
+	CompletedAt  time.Time `json:"completed_at,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+	Tasks        []Task    `json:"tasks,omitempty"`
+}
+
+// ProcessProgress counts the tasks of a process by status
+type ProcessProgress struct {
+	Process   string `json:"process" example:"NFCORE_RNASEQ:FASTQC"`
+	Total     int    `json:"total"`
+	Submitted int    `json:"submitted"`
+	Running   int    `json:"running"`
+	Succeeded int    `json:"succeeded"`
+	Cached    int    `json:"cached"`
+	Failed    int    `json:"failed"`
+	Aborted   int    `json:"aborted"`
+}
+
+// taskStatusRank orders task statuses, so an event that arrives late
+// cannot move a task backwards
+func taskStatusRank(status string) int {
+	switch status {
+	case "", TaskNew:
+		return 0
+	case TaskSubmitted:
+		return 1
+	case TaskRunning:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Apply updates the progress with a weblog event
+func (p *WorkflowProgress) Apply(event WeblogEvent) {
+	// A retried head node runs Nextflow again under a new run ID
+	if event.Event == WeblogStarted && p.RunID != "" && event.RunID != p.RunID {
+		*p = WorkflowProgress{JobID: p.JobID}
+	}
+	if event.RunName != "" {
+		p.RunName = event.RunName
+	}
+	if event.RunID != "" {
+		p.RunID = event.RunID
+	}
+	if p.Status == "" {
+		p.Status = WorkflowRunning
+	}
+	if !event.UTCTime.IsZero() {
+		p.UpdatedAt = event.UTCTime
+	}
+
+	switch event.Event {
+	case WeblogStarted:
+		p.Status = WorkflowRunning
+		p.StartedAt = event.UTCTime
+	case WeblogCompleted:
+		p.Status = WorkflowFailed
+		if event.Metadata != nil {
+			if event.Metadata.Workflow.Success {
+				p.Status = WorkflowSucceeded
+			}
+			p.ErrorMessage = event.Metadata.Workflow.ErrorMessage
+		}
+		p.CompletedAt = event.UTCTime
+	}
+	if event.Trace != nil {
+		p.applyTrace(*event.Trace)
+	}
+}
+
+// applyTrace records the state of a task, keeping tasks ordered by ID
+func (p *WorkflowProgress) applyTrace(trace WeblogTrace) {
+	i := sort.Search(len(p.Tasks), func(i int) bool { return p.Tasks[i].TaskID >= trace.TaskID })
+	if i == len(p.Tasks) || p.Tasks[i].TaskID != trace.TaskID {
+		p.Tasks = append(p.Tasks, Task{})
+		copy(p.Tasks[i+1:], p.Tasks[i:])
+		p.Tasks[i] = Task{TaskID: trace.TaskID}
+	}
+	task := &p.Tasks[i]
+	if trace.Status != "" && taskStatusRank(trace.Status) < taskStatusRank(task.Status) {
+		return
+	}
+
+	// Later records are more complete, but keep what earlier ones told us
+	for field, value := range map[*string]string{
+		&task.Hash:     trace.Hash,
+		&task.Name:     trace.Name,
+		&task.Process:  trace.Process,
+		&task.Tag:      trace.Tag,
+		&task.Status:   trace.Status,
+		&task.NativeID: string(trace.NativeID),
+		&task.WorkDir:  trace.Workdir,
+	} {
+		if value != "" {
+			*field = value
+		}
+	}
+	if trace.Attempt > 0 {
+		task.Attempt = trace.Attempt
+	}
+	if trace.Exit != nil {
+		task.Exit = trace.Exit
+	}
+	if trace.Duration > 0 {
+		task.Duration = trace.Duration
+	}
+	if trace.Realtime > 0 {
+		task.Realtime = trace.Realtime
+	}
+	if trace.Submit > 0 {
+		task.SubmittedAt = time.UnixMilli(trace.Submit).UTC()
+	}
+	if trace.Start > 0 {
+		task.StartedAt = time.UnixMilli(trace.Start).UTC()
+	}
+	if trace.Complete > 0 {
+		task.CompletedAt = time.UnixMilli(trace.Complete).UTC()
+	}
+}
+
+// Processes counts the tasks of every process, in the order the processes
+// first submitted a task
+func (p WorkflowProgress) Processes() []ProcessProgress {
+	processes := make([]ProcessProgress, 0)
+	index := make(map[string]int)
+	for _, task := range p.Tasks {
+		i, ok := index[task.Process]
+		if !ok {
+			i = len(processes)
+			index[task.Process] = i
+			processes = append(processes, ProcessProgress{Process: task.Process})
+		}
+		processes[i].add(task.Status)
+	}
+	return processes
+}
+
+// Total counts every task of the run by status
+func (p WorkflowProgress) Total() ProcessProgress {
+	var total ProcessProgress
+	for _, task := range p.Tasks {
+		total.add(task.Status)
+	}
+	return total
+}
+
+func (c *ProcessProgress) add(status string) {
+	c.Total++
+	switch strings.ToUpper(status) {
+	case TaskRunning:
+		c.Running++
+	case TaskCompleted:
+		c.Succeeded++
+	case TaskCached:
+		c.Cached++
+	case TaskFailed:
+		c.Failed++
+	case TaskAborted:
+		c.Aborted++
+	default:
+		c.Submitted++
+	}
+}
+
+// MergeTasks combines the tasks reported through the weblog with those read
+// from the trace, ordered by task ID. The trace has resource usage but is
+// uploaded in batches, so a task keeps its weblog status when that is
+// further along.
+func MergeTasks(live, traced []Task) []Task {
+	byID := make(map[int]Task, len(live)+len(traced))
+	for _, task := range live {
+		byID[task.TaskID] = task
+	}
+	for _, task := range traced {
+		if current, ok := byID[task.TaskID]; ok && taskStatusRank(current.Status) > taskStatusRank(task.Status) {
+			task.Status = current.Status
+			task.Exit = current.Exit
+			task.StartedAt = current.StartedAt
+			task.CompletedAt = current.CompletedAt
+			task.Duration = current.Duration
+			task.Realtime = current.Realtime
+		}
+		byID[task.TaskID] = task
+	}
+
+	tasks := make([]Task, 0, len(byID))
+	for _, task := range byID {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].TaskID < tasks[j].TaskID })
+	return tasks
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWorkflowProgressApply(t *testing.T) {
+	events := []string{
+		`{"runName":"happy_turing","runId":"r1","event":"started","utcTime":"2025-03-01T10:00:00Z"}`,
+		`{"runId":"r1","event":"process_submitted","utcTime":"2025-03-01T10:00:01Z","trace":{"task_id":2,"process":"FASTQC","name":"FASTQC (b)","status":"SUBMITTED"}}`,
+		`{"runId":"r1","event":"process_submitted","utcTime":"2025-03-01T10:00:01Z","trace":{"task_id":1,"process":"FASTQC","name":"FASTQC (a)","status":"SUBMITTED","native_id":1234}}`,
+		`{"runId":"r1","event":"process_completed","utcTime":"2025-03-01T10:05:00Z","trace":{"task_id":1,"status":"COMPLETED","exit":0,"native_id":"batch-1"}}`,
+		// Arrives after the task completed and must not undo that
+		`{"runId":"r1","event":"process_started","utcTime":"2025-03-01T10:05:00Z","trace":{"task_id":1,"process":"FASTQC","status":"RUNNING"}}`,
+		`{"runId":"r1","event":"process_completed","utcTime":"2025-03-01T10:06:00Z","trace":{"task_id":2,"process":"FASTQC","status":"FAILED","exit":1}}`,
+		`{"runId":"r1","event":"process_submitted","utcTime":"2025-03-01T10:06:00Z","trace":{"task_id":3,"process":"MULTIQC","status":"SUBMITTED"}}`,
+		`{"runId":"r1","event":"completed","utcTime":"2025-03-01T10:10:00Z","metadata":{"workflow":{"success":false,"errorMessage":"FASTQC (b) failed"}}}`,
+	}
+
+	p := WorkflowProgress{JobID: "job"}
+	for _, data := range events {
+		var event WeblogEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("unmarshal %s: %v", data, err)
+		}
+		p.Apply(event)
+	}
+
+	if p.RunName != "happy_turing" || p.Status != WorkflowFailed || p.ErrorMessage != "FASTQC (b) failed" {
+		t.Errorf("unexpected run state %+v", p)
+	}
+	if len(p.Tasks) != 3 || p.Tasks[0].TaskID != 1 || p.Tasks[2].TaskID != 3 {
+		t.Fatalf("expected tasks ordered by ID, got %+v", p.Tasks)
+	}
+	if task := p.Tasks[0]; task.Status != TaskCompleted || task.NativeID != "batch-1" || task.Name != "FASTQC (a)" {
+		t.Errorf("unexpected task 1 %+v", task)
+	}
+
+	processes := p.Processes()
+	if len(processes) != 2 || processes[0].Process != "FASTQC" || processes[1].Process != "MULTIQC" {
+		t.Fatalf("unexpected processes %+v", processes)
+	}
+	if fastqc := processes[0]; fastqc.Total != 2 || fastqc.Succeeded != 1 || fastqc.Failed != 1 {
+		t.Errorf("unexpected FASTQC counts %+v", fastqc)
+	}
+	if total := p.Total(); total.Total != 3 || total.Submitted != 1 {
+		t.Errorf("unexpected totals %+v", total)
+	}
+
+	// A retried head node starts over
+	p.Apply(WeblogEvent{RunID: "r2", Event: WeblogStarted})
+	if len(p.Tasks) != 0 || p.Status != WorkflowRunning || p.JobID != "job" {
+		t.Errorf("expected a fresh run, got %+v", p)
+	}
+}
+
+func TestMergeTasks(t *testing.T) {
+	live := []Task{
+		{TaskID: 1, Process: "FASTQC", Status: TaskCompleted},
+		{TaskID: 3, Process: "MULTIQC", Status: TaskSubmitted},
+	}
+	traced := []Task{
+		{TaskID: 1, Process: "FASTQC", Status: TaskRunning, PeakRSS: 1024},
+		{TaskID: 2, Process: "FASTQC", Status: TaskFailed},
+	}
+
+	tasks := MergeTasks(live, traced)
+	if len(tasks) != 3 || tasks[0].TaskID != 1 || tasks[1].TaskID != 2 || tasks[2].TaskID != 3 {
+		t.Fatalf("expected tasks 1-3 in order, got %+v", tasks)
+	}
+	if tasks[0].Status != TaskCompleted || tasks[0].PeakRSS != 1024 {
+		t.Errorf("expected the weblog status with the trace usage, got %+v", tasks[0])
+	}
+}
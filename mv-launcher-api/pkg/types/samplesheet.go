@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+// SamplesheetSpec describes the columns a pipeline expects in its input
+// samplesheet
+type SamplesheetSpec struct {
+	Columns []SamplesheetColumn `json:"columns"`
+}
+
+// SamplesheetColumn is a single samplesheet column
+type SamplesheetColumn struct {
+	Name     string `json:"name" example:"fastq_1"`
+	Required bool   `json:"required,omitempty"`
+	// File columns hold S3 URIs that must exist when the sheet is uploaded
+	File    bool   `json:"file,omitempty"`
+	Pattern string `json:"pattern,omitempty" example:"^\\S+\\.f(ast)?q\\.gz$"`
+}
+
+// Samplesheet is an uploaded samplesheet jobs can use as their input
+type Samplesheet struct {
+	ID        string    `json:"id"`
+	Pipeline  string    `json:"pipeline,omitempty" example:"rnaseq"`
+	FileName  string    `json:"file_name" example:"samplesheet.csv"`
+	Format    string    `json:"format" example:"csv"`
+	Columns   []string  `json:"columns"`
+	Rows      int       `json:"rows"`
+	URI       string    `json:"uri" example:"s3://nextflow-jobs/samplesheets/1b4e28ba/samplesheet.csv"`
+	CreatedAt time.Time `json:"created_at"`
+}
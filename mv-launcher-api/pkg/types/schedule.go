@@ -0,0 +1,178 @@
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Overlap policies decide what a schedule does when it fires while the job
+// it launched last is still running
+const (
+	// OverlapSkip drops the run
+	OverlapSkip = "skip"
+	// OverlapQueue launches the run once the running job finishes
+	OverlapQueue = "queue"
+	// OverlapAllow launches the run anyway
+	OverlapAllow = "allow"
+)
+
+const (
+	// MaxScheduleRuns is how many runs a schedule keeps in its history
+	MaxScheduleRuns = 100
+	// MaxQueuedScheduleRuns is how many runs a schedule with the queue
+	// policy holds back before it starts skipping them
+	MaxQueuedScheduleRuns = 10
+)
+
+// Schedule launches a job from a template on a cron schedule
+type Schedule struct {
+	ID   string `json:"id"`
+	Name string `json:"name" example:"nightly-qc"`
+	// Cron is a standard five-field cron expression or a descriptor such
+	// as @daily
+	Cron       string        `json:"cron" example:"0 2 * * *"`
+	Timezone   string        `json:"timezone,omitempty" example:"Europe/Berlin"`
+	Overlap    string        `json:"overlap,omitempty" example:"skip"`
+	Paused     bool          `json:"paused,omitempty"`
+	Template   Job           `json:"template"`
+	NextRunAt  time.Time     `json:"next_run_at,omitempty"`
+	QueuedRuns []time.Time   `json:"queued_runs,omitempty"`
+	Runs       []ScheduleRun `json:"runs,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// ScheduleRun is a time a schedule fired and what came of it
+type ScheduleRun struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+	At          time.Time `json:"at"`
+	JobID       string    `json:"job_id,omitempty"`
+	Outcome     string    `json:"outcome" example:"launched"`
+	Message     string    `json:"message,omitempty"`
+}
+
+// Outcomes of a schedule run
+const (
+	ScheduleRunLaunched = "launched"
+	ScheduleRunQueued   = "queued"
+	ScheduleRunSkipped  = "skipped"
+	ScheduleRunFailed   = "failed"
+)
+
+// Verify checks a schedule for errors and fills in its defaults. The job
+// template is checked when a run is launched.
+func (s *Schedule) Verify() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Cron = strings.TrimSpace(s.Cron)
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	if s.Overlap == "" {
+		s.Overlap = OverlapSkip
+	}
+
+	var errs ValidationErrors
+	if s.Name == "" {
+		errs.Add("name", "is required")
+	}
+	if s.Cron == "" {
+		errs.Add("cron", "is required")
+	} else if _, err := cron.ParseStandard(s.Cron); err != nil {
+		errs.Add("cron", "is not a valid cron expression: %v", err)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		errs.Add("timezone", "is not a known time zone")
+	}
+	switch s.Overlap {
+	case OverlapSkip, OverlapQueue, OverlapAllow:
+	default:
+		errs.Add("overlap", "must be one of %s, %s or %s", OverlapSkip, OverlapQueue, OverlapAllow)
+	}
+	if s.Template.Pipeline == "" {
+		errs.Add("template.pipeline", "is required")
+	}
+	return errs.Err()
+}
+
+// Next returns the first time after t the schedule fires, in its time zone
+func (s Schedule) Next(t time.Time) (time.Time, error) {
+	schedule, err := cron.ParseStandard(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(t.In(loc)).UTC(), nil
+}
+
+// Tick works out which runs of a schedule to launch at now, given whether
+// the job it launched last is still running. A held-back run is released
+// once that job finished, and counts as running from then on. When the
+// schedule is due it moves on to its next run; runs missed while the
+// launcher was down are coalesced into the one it was due for, which the
+// overlap policy launches, queues or skips. Tick returns the scheduled
+// times of the runs to launch and reports whether the schedule changed.
+func (s *Schedule) Tick(now time.Time, active bool) ([]time.Time, bool, error) {
+	var launch []time.Time
+	changed := false
+	if len(s.QueuedRuns) > 0 && !active {
+		launch = append(launch, s.QueuedRuns[0])
+		s.QueuedRuns = s.QueuedRuns[1:]
+		active = true
+		changed = true
+	}
+
+	if !s.NextRunAt.IsZero() && now.Before(s.NextRunAt) {
+		return launch, changed, nil
+	}
+	scheduledAt := s.NextRunAt
+	next, err := s.Next(now)
+	if err != nil {
+		return launch, changed, err
+	}
+	s.NextRunAt = next
+	if scheduledAt.IsZero() {
+		return launch, true, nil
+	}
+
+	switch {
+	case !active || s.Overlap == OverlapAllow:
+		launch = append(launch, scheduledAt)
+	case s.Overlap == OverlapQueue && len(s.QueuedRuns) < MaxQueuedScheduleRuns:
+		s.QueuedRuns = append(s.QueuedRuns, scheduledAt)
+		s.RecordRun(ScheduleRun{
+			ScheduledAt: scheduledAt,
+			At:          now,
+			Outcome:     ScheduleRunQueued,
+			Message:     "Previous run is still running",
+		})
+	default:
+		s.RecordRun(ScheduleRun{
+			ScheduledAt: scheduledAt,
+			At:          now,
+			Outcome:     ScheduleRunSkipped,
+			Message:     "Previous run is still running",
+		})
+	}
+	return launch, true, nil
+}
+
+// RecordRun appends a run to the history of the schedule, dropping the
+// oldest runs beyond MaxScheduleRuns
+func (s *Schedule) RecordRun(run ScheduleRun) {
+	s.Runs = append(s.Runs, run)
+	if len(s.Runs) > MaxScheduleRuns {
+		s.Runs = s.Runs[len(s.Runs)-MaxScheduleRuns:]
+	}
+}
+
+// Redacted returns a copy of the schedule without the AWS credentials of
+// its template
+func (s Schedule) Redacted() Schedule {
+	s.Template = s.Template.Redacted()
+	return s
+}
@@ -0,0 +1,132 @@
+package types
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleVerify(t *testing.T) {
+	s := Schedule{Name: "nightly-qc", Cron: "0 2 * * *", Template: Job{Pipeline: "nf-core/fastqc"}}
+	if err := s.Verify(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Timezone != "UTC" || s.Overlap != OverlapSkip {
+		t.Errorf("expected defaults, got timezone %q and overlap %q", s.Timezone, s.Overlap)
+	}
+
+	s = Schedule{Cron: "61 * * * *", Timezone: "Mars/Olympus", Overlap: "sometimes"}
+	var errs ValidationErrors
+	if !errors.As(s.Verify(), &errs) {
+		t.Fatal("expected validation errors")
+	}
+	fields := make(map[string]bool)
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, field := range []string{"name", "cron", "timezone", "overlap", "template.pipeline"} {
+		if !fields[field] {
+			t.Errorf("expected an error for %s, got %v", field, errs)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	s := Schedule{Cron: "0 2 * * *", Timezone: "Europe/Berlin"}
+	// 01:30 UTC is 03:30 in Berlin in summer, so the next 02:00 Berlin is
+	// the following day at 00:00 UTC
+	now := time.Date(2025, 7, 1, 1, 30, 0, 0, time.UTC)
+	next, err := s.Next(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := time.Date(2025, 7, 2, 0, 0, 0, 0, time.UTC)
+	if !next.Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, next)
+	}
+}
+
+func TestScheduleRecordRun(t *testing.T) {
+	var s Schedule
+	for i := 0; i < MaxScheduleRuns+5; i++ {
+		s.RecordRun(ScheduleRun{JobID: string(rune('a' + i%26))})
+	}
+	if len(s.Runs) != MaxScheduleRuns {
+		t.Errorf("expected %d runs, got %d", MaxScheduleRuns, len(s.Runs))
+	}
+}
+
+func TestScheduleTick(t *testing.T) {
+	due := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	now := due.Add(time.Minute)
+	tests := []struct {
+		name       string
+		overlap    string
+		active     bool
+		queued     int
+		wantLaunch int
+		wantQueued int
+		wantRun    string // Outcome recorded by Tick
+	}{
+		{"idle", OverlapSkip, false, 0, 1, 0, ""},
+		{"skip", OverlapSkip, true, 0, 0, 0, ScheduleRunSkipped},
+		{"queue", OverlapQueue, true, 0, 0, 1, ScheduleRunQueued},
+		{"queue full", OverlapQueue, true, MaxQueuedScheduleRuns, 0, MaxQueuedScheduleRuns, ScheduleRunSkipped},
+		{"allow", OverlapAllow, true, 0, 1, 0, ""},
+		// The held-back run is launched and the due one waits behind it
+		{"release", OverlapQueue, false, 2, 1, 2, ScheduleRunQueued},
+	}
+	for _, tt := range tests {
+		s := Schedule{Cron: "0 2 * * *", Timezone: "UTC", Overlap: tt.overlap, NextRunAt: due}
+		for i := 0; i < tt.queued; i++ {
+			s.QueuedRuns = append(s.QueuedRuns, due.Add(-time.Duration(i+1)*24*time.Hour))
+		}
+
+		launch, changed, err := s.Tick(now, tt.active)
+		if err != nil || !changed {
+			t.Fatalf("%s: expected a change, got %v, %v", tt.name, changed, err)
+		}
+		if len(launch) != tt.wantLaunch || len(s.QueuedRuns) != tt.wantQueued {
+			t.Errorf("%s: launched %v with %d queued, want %d launched and %d queued", tt.name, launch, len(s.QueuedRuns), tt.wantLaunch, tt.wantQueued)
+		}
+		outcome := ""
+		if len(s.Runs) > 0 {
+			outcome = s.Runs[len(s.Runs)-1].Outcome
+		}
+		if len(s.Runs) > 1 || outcome != tt.wantRun {
+			t.Errorf("%s: recorded %v, want a %q run", tt.name, s.Runs, tt.wantRun)
+		}
+		if want := due.Add(24 * time.Hour); !s.NextRunAt.Equal(want) {
+			t.Errorf("%s: expected the next run at %s, got %s", tt.name, want, s.NextRunAt)
+		}
+	}
+}
+
+func TestScheduleTickCoalesces(t *testing.T) {
+	// The launcher was down for three days
+	missed := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	now := missed.Add(3*24*time.Hour + time.Hour)
+	s := Schedule{Cron: "0 2 * * *", Timezone: "UTC", Overlap: OverlapSkip, NextRunAt: missed}
+
+	launch, _, err := s.Tick(now, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(launch) != 1 || !launch[0].Equal(missed) {
+		t.Errorf("expected one run for %s, got %v", missed, launch)
+	}
+	if want := time.Date(2025, 7, 5, 2, 0, 0, 0, time.UTC); !s.NextRunAt.Equal(want) {
+		t.Errorf("expected the next run at %s, got %s", want, s.NextRunAt)
+	}
+
+	// Not due again until then
+	if launch, changed, _ := s.Tick(now.Add(time.Minute), false); len(launch) != 0 || changed {
+		t.Errorf("expected nothing to do, got %v, %v", launch, changed)
+	}
+
+	// A new schedule only works out its first run
+	s = Schedule{Cron: "0 2 * * *", Timezone: "UTC"}
+	if launch, changed, _ := s.Tick(now, false); len(launch) != 0 || !changed || s.NextRunAt.IsZero() {
+		t.Errorf("expected only the first run to be set, got %v, %v, %s", launch, changed, s.NextRunAt)
+	}
+}
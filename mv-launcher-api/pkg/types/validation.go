@@ -0,0 +1,36 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes a problem with a single field of a request
+type FieldError struct {
+	Field   string `json:"field" example:"work_dir"`
+	Message string `json:"message" example:"is required"`
+}
+
+// ValidationErrors collects every field error found in a request
+type ValidationErrors []FieldError
+
+// Add records a field error
+func (v *ValidationErrors) Add(field, format string, args ...interface{}) {
+	*v = append(*v, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns v as an error, or nil if there are no field errors
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fieldErr := range v {
+		messages = append(messages, fieldErr.Field+" "+fieldErr.Message)
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
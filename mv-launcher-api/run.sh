@@ -7,89 +7,52 @@ if [ -z "$JOB_ID" ]; then
     exit 1
 fi
 
-# Set the job spec path
-JOB_SPEC_PATH="s3://usw2-nextflow-pipeline-workflow/job-${JOB_ID}.json"
-echo "Downloading job spec from S3: $JOB_SPEC_PATH"
-
-# Check if the file exists in S3 before trying to download
-if ! aws s3 ls "$JOB_SPEC_PATH" > /dev/null 2>&1; then
-    echo "Error: Job spec file not found in S3: $JOB_SPEC_PATH"
+# Validate required environment
+if [ -z "$JOB_BUCKET" ] || [ -z "$PIPELINE" ] || [ -z "$WORK_DIR" ] || [ -z "$RESULT_DIR" ]; then
+    echo "Error: Missing required environment variables"
+    echo "Job Bucket: $JOB_BUCKET"
+    echo "Pipeline: $PIPELINE"
+    echo "Work Dir: $WORK_DIR"
+    echo "Result Dir: $RESULT_DIR"
     exit 1
 fi
 
-# Download the job spec to a temporary file
-TEMP_FILE=$(mktemp)
-aws s3 cp "$JOB_SPEC_PATH" "$TEMP_FILE"
-if [ $? -ne 0 ]; then
-    echo "Error: Failed to download job spec from S3"
+# The launcher API renders the Nextflow config and stores it next to the
+# job spec
+config_path="s3://${JOB_BUCKET}/jobs/${JOB_ID}/nextflow.config"
+echo "Downloading Nextflow config from S3: $config_path"
+if ! aws s3 cp "$config_path" aws.config; then
+    echo "Error: Failed to download Nextflow config from S3: $config_path"
     exit 1
 fi
 
-# Read the job spec from the temporary file
-job=$(cat "$TEMP_FILE")
-rm "$TEMP_FILE"
-
-# Parse fields from JSON with error handling
-name=$(echo "$job" | jq -r .name)
-pipeline=$(echo "$job" | jq -r .pipeline)
-profile=$(echo "$job" | jq -r .profile)
-head_node_queue=$(echo "$job" | jq -r .head_node_queue)
-task_queue=$(echo "$job" | jq -r .task_queue)
-work_dir=$(echo "$job" | jq -r .work_dir)
-result_dir=$(echo "$job" | jq -r .result_dir)
-memory=$(echo "$job" | jq -r .memory)
-max_retries=$(echo "$job" | jq -r .max_retries)
-
-# Set defaults if not provided
-memory=${memory:-"20G"}
-max_retries=${max_retries:-5}
-
-# Validate required fields
-if [ -z "$pipeline" ] || [ -z "$profile" ] || [ -z "$work_dir" ] || [ -z "$result_dir" ] || [ -z "$task_queue" ]; then
-    echo "Error: Missing required fields in job spec"
-    echo "Pipeline: $pipeline"
-    echo "Profile: $profile"
-    echo "Work Dir: $work_dir"
-    echo "Result Dir: $result_dir"
-    echo "Task Queue: $task_queue"
-    exit 1
+# Pipeline parameters are staged as a params file when the job has any
+params_args=()
+if [ -n "$PARAMS_FILE" ]; then
+    params_path="s3://${JOB_BUCKET}/jobs/${JOB_ID}/${PARAMS_FILE}"
+    echo "Downloading pipeline parameters from S3: $params_path"
+    if ! aws s3 cp "$params_path" "$PARAMS_FILE"; then
+        echo "Error: Failed to download pipeline parameters from S3: $params_path"
+        exit 1
+    fi
+    params_args=(-params-file "$PARAMS_FILE")
 fi
 
-# Pull credentials from env (if present)
-access_key="${AWS_ACCESS_KEY_ID:-}"
-secret_key="${AWS_SECRET_ACCESS_KEY:-}"
-
-echo "Generating aws.config with dynamic credentials..."
-
-cat > aws.config <<EOF
-plugins {
-    id 'nf-amazon'
-}
-process {
-    executor = 'awsbatch'
-    queue = '${task_queue}'
-    maxRetries = ${max_retries}
-    memory = '${memory}'
-}
-process.containerOptions = '--env MMC_CHECKPOINT_DIAGNOSIS=true --env MMC_CHECKPOINT_IMAGE_SUBPATH=nextflow --env MMC_CHECKPOINT_INTERVAL=5m --env MMC_CHECKPOINT_MODE=true --env MMC_CHECKPOINT_IMAGE_PATH=/mmc-checkpoint'
-
-aws {
-    ${access_key:+accessKey = '${access_key}'}
-    ${secret_key:+secretKey = '${secret_key}'}
-    region = 'us-west-2'
-    client {
-        maxConnections = 20
-        connectionTimeout = 10000
-        uploadStorageClass = 'INTELLIGENT_TIERING'
-        storageEncryption = 'AES256'
-    }
-    batch {
-        cliPath = '/nextflow_awscli/bin/aws'
-        maxTransferAttempts = 3
-        delayBetweenAttempts = '5 sec'
-    }
-}
-EOF
+revision_args=()
+if [ -n "$REVISION" ]; then
+    revision_args=(-r "$REVISION")
+fi
+
+profile_args=()
+if [ -n "$PROFILE" ]; then
+    profile_args=(-profile "$PROFILE")
+fi
+
+# Report task progress to the launcher API when it is reachable
+weblog_args=()
+if [ -n "$WEBLOG_URL" ]; then
+    weblog_args=(-with-weblog "$WEBLOG_URL")
+fi
 
 # Create log directory if it doesn't exist
 mkdir -p /var/log/nextflow
@@ -97,13 +60,74 @@ mkdir -p /var/log/nextflow
 # Create work directory if it doesn't exist
 mkdir -p /workspace/work
 
-echo "Running Nextflow pipeline: $pipeline"
-nextflow -log "$NEXTFLOW_LOG_PATH" run "$pipeline" \
-    -profile "$profile" \
-    -work-dir "$work_dir" \
-    --outdir "$result_dir" \
+# Restore the .nextflow cache of the attempt being resumed
+resume_args=()
+if [ -n "$RESUME_FROM" ]; then
+    s3_cache_path="s3://${LOG_BUCKET}/jobs/${RESUME_FROM}/nextflow-cache"
+    echo "Restoring Nextflow cache from $s3_cache_path"
+    if ! aws s3 sync "$s3_cache_path" .nextflow --only-show-errors; then
+        echo "Warning: Failed to restore Nextflow cache, resume may start from scratch"
+    fi
+    resume_args=(-resume ${SESSION_ID})
+fi
+
+# Upload the trace while the run progresses, so the API can list its tasks
+# before it finishes
+(
+    while sleep "${TRACE_UPLOAD_INTERVAL:-60}"; do
+        if [ -f trace.txt ]; then
+            aws s3 cp trace.txt "s3://${LOG_BUCKET}/jobs/${JOB_ID}/trace.txt" --only-show-errors || true
+        fi
+    done
+) &
+trace_uploader=$!
+
+echo "Running Nextflow pipeline: $PIPELINE ${REVISION:+(revision $REVISION)}"
+set +e
+nextflow -log "$NEXTFLOW_LOG_PATH" run "$PIPELINE" \
+    "${revision_args[@]}" \
+    "${profile_args[@]}" \
+    -work-dir "$WORK_DIR" \
+    --outdir "$RESULT_DIR" \
     -c aws.config \
-    -ansi-log false
+    -ansi-log false \
+    "${params_args[@]}" \
+    "${weblog_args[@]}" \
+    "${resume_args[@]}"
+nextflow_exit=$?
+kill "$trace_uploader" 2>/dev/null
+set -e
+
+# Record the full SHA of the commit the run used, so resuming can pin it.
+# Nextflow clones repositories under its assets dir without needing git.
+repo_path="$PIPELINE"
+if [[ "$repo_path" == *://* ]]; then
+    repo_path="${repo_path#*://}"
+    repo_path="${repo_path#*/}"
+fi
+git_dir="${NXF_ASSETS:-$HOME/.nextflow/assets}/${repo_path%.git}/.git"
+if [ -f "$git_dir/HEAD" ]; then
+    commit_id=$(cat "$git_dir/HEAD")
+    if [[ "$commit_id" == ref:* ]]; then
+        ref="${commit_id#ref: }"
+        commit_id=$(cat "$git_dir/$ref" 2>/dev/null || grep " $ref\$" "$git_dir/packed-refs" 2>/dev/null | cut -d' ' -f1)
+    fi
+    if [ -n "$commit_id" ]; then
+        echo "$commit_id" | aws s3 cp - "s3://${LOG_BUCKET}/jobs/${JOB_ID}/commit_id" \
+            || echo "Warning: Failed to upload commit ID to S3"
+    fi
+fi
+
+# Persist the session ID and .nextflow cache so the run can be resumed from
+# a fresh container
+if [ -f .nextflow/history ]; then
+    tail -n 1 .nextflow/history | cut -f 6 | aws s3 cp - "s3://${LOG_BUCKET}/jobs/${JOB_ID}/session_id" \
+        || echo "Warning: Failed to upload session ID to S3"
+fi
+if [ -d .nextflow ]; then
+    aws s3 sync .nextflow "s3://${LOG_BUCKET}/jobs/${JOB_ID}/nextflow-cache" --only-show-errors \
+        || echo "Warning: Failed to upload Nextflow cache to S3"
+fi
 
 # Upload Nextflow logs to S3
 if [ -f "$NEXTFLOW_LOG_PATH" ]; then
@@ -139,3 +163,5 @@ if [ -f "pipeline_dag.html" ]; then
         echo "Uploaded pipeline_dag.html to $s3_path"
     fi
 fi
+
+exit $nextflow_exit